@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -13,9 +14,15 @@ import (
 	"github.com/redis/go-redis/v9"
 	"github.com/shohag/seentics-email/internal/config"
 	"github.com/shohag/seentics-email/internal/database"
+	"github.com/shohag/seentics-email/internal/domains"
 	"github.com/shohag/seentics-email/internal/handlers"
+	"github.com/shohag/seentics-email/internal/mailer"
+	"github.com/shohag/seentics-email/internal/metrics"
 	"github.com/shohag/seentics-email/internal/middleware"
 	"github.com/shohag/seentics-email/internal/postal"
+	"github.com/shohag/seentics-email/internal/queue"
+	"github.com/shohag/seentics-email/internal/scheduler"
+	"github.com/shohag/seentics-email/internal/webhooks"
 )
 
 func main() {
@@ -50,24 +57,71 @@ func main() {
 	// Initialize Postal client
 	postalClient := postal.NewClient(cfg.PostalAPIURL, cfg.PostalAPIKey)
 
+	// Initialize send and webhook queues (inline sending without Redis)
+	var sendQueue, webhookQueue *queue.Queue
+	if redisClient != nil {
+		sendQueue = queue.New(redisClient, "send", cfg.SendVisibilityTimeout)
+		webhookQueue = queue.New(redisClient, "webhooks", cfg.WebhookVisibilityTimeout)
+	}
+	emailMailer := mailer.New(cfg, postalClient, sendQueue)
+
+	// Initialize webhook dispatcher
+	webhookDispatcher := webhooks.NewDispatcher(cfg, webhookQueue)
+
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	var sendWorkers *queue.WorkerPool
+	if sendQueue != nil {
+		sendWorkers = queue.NewWorkerPool(sendQueue, emailMailer.HandleJob, emailMailer.HandleDeadJob,
+			cfg.SendWorkers, cfg.SendMaxAttempts, cfg.SendRetryBaseDelay)
+		sendWorkers.Start(workerCtx)
+	}
+
+	var webhookWorkers *queue.WorkerPool
+	if webhookQueue != nil {
+		webhookWorkers = queue.NewWorkerPool(webhookQueue, webhookDispatcher.HandleJob, webhookDispatcher.HandleDeadJob,
+			cfg.WebhookWorkers, cfg.WebhookMaxAttempts, cfg.WebhookRetryBaseDelay)
+		webhookWorkers.Start(workerCtx)
+	}
+
+	emailScheduler := scheduler.New(emailMailer, cfg.SchedulerInterval)
+	emailScheduler.Start(workerCtx)
+
+	domainProvisioner, err := domains.NewProvisioner(cfg, postalClient)
+	if err != nil {
+		log.Fatalf("Failed to configure domain provisioning: %v", err)
+	}
+	domainVerifier := domains.NewVerifier(net.DefaultResolver)
+	domainMonitor := domains.NewMonitor(domainVerifier, domainProvisioner, webhookDispatcher, cfg.DomainRecheckInterval, cfg.DomainPendingExpiry)
+	domainMonitor.Start(workerCtx)
+
 	// Initialize handlers
 	authHandler := handlers.NewAuthHandler(cfg)
 	apiKeyHandler := handlers.NewAPIKeyHandler()
-	emailHandler := handlers.NewEmailHandler(postalClient)
-	domainHandler := handlers.NewDomainHandler()
-	webhookHandler := handlers.NewWebhookHandler()
+	emailHandler := handlers.NewEmailHandler(cfg, postalClient, emailMailer)
+	domainHandler := handlers.NewDomainHandler(domainVerifier, domainProvisioner)
+	webhookHandler := handlers.NewWebhookHandler(cfg, webhookDispatcher, redisClient, postalClient)
+	notificationHandler := handlers.NewNotificationHandler()
+	templateHandler := handlers.NewTemplateHandler(cfg, postalClient)
+	testRecipientHandler := handlers.NewTestRecipientHandler()
+	suppressionHandler := handlers.NewSuppressionHandler()
 
 	// Initialize middleware
 	apiKeyMiddleware := middleware.NewAPIKeyMiddleware(redisClient)
+	idempotencyMiddleware := middleware.NewIdempotencyMiddleware(redisClient)
+	postalSignatureMiddleware, err := middleware.NewPostalSignatureMiddleware(cfg)
+	if err != nil {
+		log.Fatalf("Failed to configure Postal webhook verification: %v", err)
+	}
 
 	// Setup Gin router
-	router := gin.Default()
+	router := gin.New()
+	router.Use(middleware.Logger(), gin.Recovery())
 
 	// CORS middleware
 	router.Use(func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-API-Key")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-API-Key, Idempotency-Key")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
@@ -82,6 +136,9 @@ func main() {
 		c.JSON(200, gin.H{"status": "ok"})
 	})
 
+	// Metrics (expvar counters as JSON)
+	router.GET("/metrics", metrics.Handler())
+
 	// Public routes
 	auth := router.Group("/api/auth")
 	{
@@ -90,7 +147,10 @@ func main() {
 	}
 
 	// Webhook endpoint (public, but verified)
-	router.POST("/webhooks/postal", webhookHandler.HandlePostalWebhook)
+	router.POST("/webhooks/postal", postalSignatureMiddleware.Verify(), webhookHandler.HandlePostalWebhook)
+
+	// Complaint reports routed from the feedback address (token in query or header)
+	router.POST("/inbound/feedback", webhookHandler.HandleFeedbackReport)
 
 	// Protected routes (JWT authentication)
 	api := router.Group("/api")
@@ -108,6 +168,7 @@ func main() {
 		// Emails (requires JWT)
 		api.GET("/emails", emailHandler.ListEmails)
 		api.GET("/emails/:id", emailHandler.GetEmail)
+		api.DELETE("/emails/:id/schedule", emailHandler.CancelScheduledEmail)
 
 		// Domains
 		api.GET("/domains", domainHandler.ListDomains)
@@ -119,14 +180,46 @@ func main() {
 		// Webhooks
 		api.GET("/webhooks", webhookHandler.ListWebhooks)
 		api.POST("/webhooks", webhookHandler.CreateWebhook)
+		api.PUT("/webhooks/:id", webhookHandler.UpdateWebhook)
 		api.DELETE("/webhooks/:id", webhookHandler.DeleteWebhook)
+		api.POST("/webhooks/:id/rotate-secret", webhookHandler.RotateWebhookSecret)
+		api.POST("/webhooks/:id/test", webhookHandler.TestWebhook)
+		api.GET("/webhooks/:id/deliveries", webhookHandler.ListDeliveries)
+		api.POST("/webhooks/:id/deliveries/:delivery_id/redeliver", webhookHandler.RedeliverDelivery)
+
+		// Templates
+		api.GET("/templates", templateHandler.ListTemplates)
+		api.POST("/templates", templateHandler.CreateTemplate)
+		api.GET("/templates/:id", templateHandler.GetTemplate)
+		api.PUT("/templates/:id", templateHandler.UpdateTemplate)
+		api.DELETE("/templates/:id", templateHandler.DeleteTemplate)
+		api.GET("/templates/:id/versions", templateHandler.ListTemplateVersions)
+		api.POST("/templates/:id/preview", templateHandler.PreviewTemplate)
+		api.POST("/templates/:id/test-send", templateHandler.TestSendTemplate)
+
+		// Test recipients allowed to receive template test sends
+		api.GET("/test-recipients", testRecipientHandler.ListTestRecipients)
+		api.POST("/test-recipients", testRecipientHandler.AddTestRecipient)
+		api.DELETE("/test-recipients/:id", testRecipientHandler.DeleteTestRecipient)
+
+		// Suppressions
+		api.GET("/suppressions", suppressionHandler.ListSuppressions)
+		api.POST("/suppressions", suppressionHandler.CreateSuppression)
+		api.DELETE("/suppressions/:id", suppressionHandler.DeleteSuppression)
+		api.GET("/suppressions/export", suppressionHandler.ExportSuppressions)
+		api.POST("/suppressions/import", suppressionHandler.ImportSuppressions)
+
+		// Notifications
+		api.GET("/notifications", notificationHandler.ListNotifications)
+		api.POST("/notifications/:id/read", notificationHandler.MarkNotificationRead)
 	}
 
-	// Email sending endpoint (API key authentication)
+	// Email sending endpoints (API key authentication)
 	send := router.Group("/api")
-	send.Use(apiKeyMiddleware.Validate())
 	{
-		send.POST("/send", emailHandler.SendEmail)
+		send.POST("/send", apiKeyMiddleware.Validate(), idempotencyMiddleware.Handle(), emailHandler.SendEmail)
+		send.POST("/send/batch", apiKeyMiddleware.ValidateWithCost(middleware.BatchCost(cfg.BatchMaxMessages)),
+			idempotencyMiddleware.Handle(), emailHandler.SendBatch)
 	}
 
 	// Graceful shutdown
@@ -156,5 +249,16 @@ func main() {
 		log.Fatalf("Server forced to shutdown: %v", err)
 	}
 
+	// Let workers finish the jobs they are processing
+	stopWorkers()
+	emailScheduler.Wait()
+	domainMonitor.Wait()
+	if sendWorkers != nil {
+		sendWorkers.Wait()
+	}
+	if webhookWorkers != nil {
+		webhookWorkers.Wait()
+	}
+
 	log.Println("Server exited")
 }
@@ -0,0 +1,53 @@
+package bounces
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/shohag/seentics-email/internal/models"
+	"github.com/shohag/seentics-email/internal/postal"
+)
+
+var (
+	mailboxFullPattern = regexp.MustCompile(`(?i)mailbox (is )?full|over ?quota|quota exceeded|exceeded (the )?(storage|quota)|insufficient (system )?storage|mailbox size limit`)
+	blockPattern       = regexp.MustCompile(`(?i)block(ed|list)|blacklist|spam|\bpolicy\b|reputation|\bdnsbl\b|\brbl\b|rejected for policy|not authorized|access denied|banned`)
+	unknownUserPattern = regexp.MustCompile(`(?i)user unknown|unknown user|no such (user|recipient|mailbox)|does not exist|doesn'?t exist|invalid (recipient|mailbox|address)|recipient (address )?rejected|address rejected|mailbox unavailable|mailbox not found|unrouteable|no mailbox`)
+)
+
+// Classify decides what kind of bounce a failure is, from its enhanced
+// status code (RFC 3463) when there is one, and otherwise from its diagnostic
+// text and SMTP reply code. Bounces that cannot be classified are treated as
+// soft so that an address is never suppressed on weak evidence.
+func Classify(info postal.BounceInfo) models.BounceType {
+	if status := info.Status; status != "" {
+		switch {
+		case strings.HasSuffix(status, ".2.2"):
+			return models.BounceTypeMailboxFull
+		case strings.HasPrefix(status, "5.1.") || status == "5.2.1" || status == "5.4.1":
+			// Bad or disabled mailbox; Office 365 uses 5.4.1 for unknown recipients
+			return models.BounceTypeHard
+		case strings.HasPrefix(status, "5.7.") || strings.HasPrefix(status, "4.7."):
+			return models.BounceTypeBlock
+		case strings.HasPrefix(status, "5."):
+			return models.BounceTypeHard
+		case strings.HasPrefix(status, "4."):
+			return models.BounceTypeSoft
+		}
+	}
+
+	text := info.Diagnostic
+	switch {
+	case mailboxFullPattern.MatchString(text):
+		return models.BounceTypeMailboxFull
+	case unknownUserPattern.MatchString(text):
+		return models.BounceTypeHard
+	case blockPattern.MatchString(text):
+		return models.BounceTypeBlock
+	case info.Code >= 500:
+		return models.BounceTypeHard
+	case info.Code >= 400:
+		return models.BounceTypeSoft
+	}
+
+	return models.BounceTypeSoft
+}
@@ -0,0 +1,100 @@
+package bounces
+
+import (
+	"testing"
+
+	"github.com/shohag/seentics-email/internal/models"
+	"github.com/shohag/seentics-email/internal/postal"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name       string
+		diagnostic string
+		want       models.BounceType
+	}{
+		{
+			name:       "Gmail unknown user",
+			diagnostic: "550-5.1.1 The email account that you tried to reach does not exist. Please try double-checking the recipient's email address for typos or unnecessary spaces. Learn more at https://support.google.com/mail/?p=NoSuchUser",
+			want:       models.BounceTypeHard,
+		},
+		{
+			name:       "Postfix unknown user mentioning policy",
+			diagnostic: "550 5.1.1 <bob@example.com>: Recipient address rejected: User unknown in virtual mailbox table; see our policy",
+			want:       models.BounceTypeHard,
+		},
+		{
+			name:       "Office 365 unknown recipient",
+			diagnostic: "550 5.4.1 Recipient address rejected: Access denied. AS(201806281) [DM6NAM12FT034.eop-nam12.prod.protection.outlook.com]",
+			want:       models.BounceTypeHard,
+		},
+		{
+			name:       "Gmail spam block",
+			diagnostic: "550-5.7.1 [203.0.113.5] Our system has detected that this message is likely unsolicited mail. To reduce the amount of spam sent to Gmail, this message has been blocked.",
+			want:       models.BounceTypeBlock,
+		},
+		{
+			name:       "Yahoo temporary deferral",
+			diagnostic: "421 4.7.0 [TSS04] Messages from 203.0.113.5 temporarily deferred due to unexpected volume or user complaints - 4.16.55.1; see https://postmaster.yahooinc.com/error-codes",
+			want:       models.BounceTypeBlock,
+		},
+		{
+			name:       "Gmail over quota",
+			diagnostic: "452-4.2.2 The email account that you tried to reach is over quota. Please direct the recipient to https://support.google.com/mail/?p=OverQuotaTemp",
+			want:       models.BounceTypeMailboxFull,
+		},
+		{
+			name:       "greylisting",
+			diagnostic: "450 4.2.0 <bob@example.com>: Recipient address rejected: Greylisted, see http://postgrey.schweikert.ch/help/example.com.html",
+			want:       models.BounceTypeSoft,
+		},
+		{
+			name:       "disabled mailbox",
+			diagnostic: "550 5.2.1 The email account that you tried to reach is disabled.",
+			want:       models.BounceTypeHard,
+		},
+		{
+			name:       "no status code, unknown mailbox",
+			diagnostic: "550 Requested action not taken: mailbox unavailable",
+			want:       models.BounceTypeHard,
+		},
+		{
+			name:       "no status code, DNSBL listing",
+			diagnostic: "554 Service unavailable; Client host [203.0.113.5] blocked using zen.spamhaus.org",
+			want:       models.BounceTypeBlock,
+		},
+		{
+			name:       "no status code, mailbox full",
+			diagnostic: "552 Mailbox is full",
+			want:       models.BounceTypeMailboxFull,
+		},
+		{
+			name:       "no status code, permanent",
+			diagnostic: "550 Not our customer",
+			want:       models.BounceTypeHard,
+		},
+		{
+			name:       "no status code, temporary",
+			diagnostic: "421 Service not available, closing transmission channel",
+			want:       models.BounceTypeSoft,
+		},
+		{
+			name:       "connection timeout",
+			diagnostic: "Connection timed out while talking to mx.example.com",
+			want:       models.BounceTypeSoft,
+		},
+		{
+			name: "empty",
+			want: models.BounceTypeSoft,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := postal.ParseDiagnostic(tt.diagnostic)
+			if got := Classify(info); got != tt.want {
+				t.Errorf("Classify(%+v) = %s, want %s", info, got, tt.want)
+			}
+		})
+	}
+}
@@ -3,6 +3,9 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
@@ -27,6 +30,64 @@ type Config struct {
 	// Postal
 	PostalAPIURL string
 	PostalAPIKey string
+
+	// Postal DNS settings published in the records of sending domains
+	PostalHostname       string
+	PostalReturnPathHost string
+	PostalSPFInclude     string
+
+	// DKIM: selector of per-domain keys, and the base64 32-byte AES key
+	// that encrypts their private keys at rest
+	DKIMSelector      string
+	DKIMEncryptionKey string
+
+	// Postal webhook verification: RSA public key, or HMAC secret as a fallback
+	PostalWebhookPublicKey string
+	PostalWebhookSecret    string
+
+	// Send queue
+	SendWorkers           int
+	SendMaxAttempts       int
+	SendRetryBaseDelay    time.Duration
+	SendVisibilityTimeout time.Duration
+	SchedulerInterval     time.Duration
+	BatchMaxMessages      int
+
+	// Sender authorization: whether a verified domain also authorizes its subdomains
+	AllowSenderSubdomains bool
+
+	// Domain monitoring: how often verified domains are re-checked, and how
+	// long a domain may stay pending before it expires
+	DomainRecheckInterval time.Duration
+	DomainPendingExpiry   time.Duration
+
+	// Attachments
+	AttachmentMaxBytes      int
+	AttachmentMaxTotalBytes int
+	AttachmentAllowedTypes  []string
+
+	// Feedback loop: ARF complaint reports that Postal routes to the
+	// feedback address are posted to /inbound/feedback with this token
+	FeedbackAddress string
+	FeedbackToken   string
+
+	// Bounce handling: soft bounces suppress an address once repeated this
+	// many times within the window
+	SoftBounceSuppressThreshold int
+	SoftBounceWindow            time.Duration
+
+	// Outbound webhooks
+	WebhookTimeout        time.Duration
+	WebhookMaxAttempts    int
+	WebhookRetryBaseDelay time.Duration
+
+	// Webhook delivery queue
+	WebhookWorkers           int
+	WebhookVisibilityTimeout time.Duration
+
+	// Webhook circuit breaker
+	WebhookDisableAfterFailures int
+	WebhookDisableAfterDuration time.Duration
 }
 
 func Load() *Config {
@@ -52,6 +113,67 @@ func Load() *Config {
 		// Postal
 		PostalAPIURL: getEnv("POSTAL_API_URL", "http://localhost:5000"),
 		PostalAPIKey: getEnv("POSTAL_API_KEY", ""),
+
+		PostalHostname:       getEnv("POSTAL_HOSTNAME", "postal.yourdomain.com"),
+		PostalReturnPathHost: getEnv("POSTAL_RETURN_PATH_HOST", "rp.postal.yourdomain.com"),
+		PostalSPFInclude:     getEnv("POSTAL_SPF_INCLUDE", "postal.yourdomain.com"),
+
+		DKIMSelector:      getEnv("DKIM_SELECTOR", "postal"),
+		DKIMEncryptionKey: getEnv("DKIM_ENCRYPTION_KEY", ""),
+
+		PostalWebhookPublicKey: getEnv("POSTAL_WEBHOOK_PUBLIC_KEY", ""),
+		PostalWebhookSecret:    getEnv("POSTAL_WEBHOOK_SECRET", ""),
+
+		// Send queue
+		SendWorkers:           getEnvInt("SEND_WORKERS", 4),
+		SendMaxAttempts:       getEnvInt("SEND_MAX_ATTEMPTS", 5),
+		SendRetryBaseDelay:    getEnvDuration("SEND_RETRY_BASE_DELAY", 30*time.Second),
+		SendVisibilityTimeout: getEnvDuration("SEND_VISIBILITY_TIMEOUT", 2*time.Minute),
+		SchedulerInterval:     getEnvDuration("SCHEDULER_INTERVAL", 15*time.Second),
+		BatchMaxMessages:      getEnvInt("BATCH_MAX_MESSAGES", 100),
+
+		AllowSenderSubdomains: getEnvBool("ALLOW_SENDER_SUBDOMAINS", false),
+
+		// Domain monitoring
+		DomainRecheckInterval: getEnvDuration("DOMAIN_RECHECK_INTERVAL", 6*time.Hour),
+		DomainPendingExpiry:   getEnvDuration("DOMAIN_PENDING_EXPIRY", 7*24*time.Hour),
+
+		// Attachments
+		AttachmentMaxBytes:      getEnvInt("ATTACHMENT_MAX_BYTES", 10<<20),
+		AttachmentMaxTotalBytes: getEnvInt("ATTACHMENT_MAX_TOTAL_BYTES", 25<<20),
+		AttachmentAllowedTypes: getEnvList("ATTACHMENT_ALLOWED_TYPES", []string{
+			"application/pdf",
+			"application/zip",
+			"application/msword",
+			"application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+			"application/vnd.ms-excel",
+			"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+			"text/plain",
+			"text/csv",
+			"text/calendar",
+			"image/*",
+		}),
+
+		// Feedback loop
+		FeedbackAddress: getEnv("FEEDBACK_ADDRESS", ""),
+		FeedbackToken:   getEnv("FEEDBACK_TOKEN", ""),
+
+		// Bounce handling
+		SoftBounceSuppressThreshold: getEnvInt("SOFT_BOUNCE_SUPPRESS_THRESHOLD", 3),
+		SoftBounceWindow:            getEnvDuration("SOFT_BOUNCE_WINDOW", 30*24*time.Hour),
+
+		// Outbound webhooks
+		WebhookTimeout:        getEnvDuration("WEBHOOK_TIMEOUT", 10*time.Second),
+		WebhookMaxAttempts:    getEnvInt("WEBHOOK_MAX_ATTEMPTS", 6),
+		WebhookRetryBaseDelay: getEnvDuration("WEBHOOK_RETRY_BASE_DELAY", 30*time.Second),
+
+		// Webhook delivery queue
+		WebhookWorkers:           getEnvInt("WEBHOOK_WORKERS", 4),
+		WebhookVisibilityTimeout: getEnvDuration("WEBHOOK_VISIBILITY_TIMEOUT", 2*time.Minute),
+
+		// Webhook circuit breaker
+		WebhookDisableAfterFailures: getEnvInt("WEBHOOK_DISABLE_AFTER_FAILURES", 50),
+		WebhookDisableAfterDuration: getEnvDuration("WEBHOOK_DISABLE_AFTER_DURATION", 72*time.Hour),
 	}
 }
 
@@ -72,3 +194,45 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func getEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
+func getEnvBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.ParseBool(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
+// getEnvList accepts a comma-separated list
+func getEnvList(key string, defaultValue []string) []string {
+	if value := os.Getenv(key); value != "" {
+		var list []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		return list
+	}
+	return defaultValue
+}
+
+// getEnvDuration accepts Go duration strings such as "30s" or "5m"
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
@@ -29,12 +29,28 @@ func Connect(cfg *config.Config) error {
 }
 
 func Migrate() error {
+	// email_logs.message_id used to be unique, which rejected every recipient
+	// after the first one of a multi-recipient send
+	if DB.Migrator().HasIndex(&models.EmailLog{}, "idx_email_logs_message_id") {
+		if err := DB.Migrator().DropIndex(&models.EmailLog{}, "idx_email_logs_message_id"); err != nil {
+			return fmt.Errorf("failed to drop legacy email log index: %w", err)
+		}
+	}
+
 	err := DB.AutoMigrate(
 		&models.User{},
 		&models.APIKey{},
 		&models.Domain{},
+		&models.Template{},
+		&models.TemplateVersion{},
+		&models.TestRecipient{},
+		&models.Suppression{},
 		&models.EmailLog{},
+		&models.ScheduledEmail{},
 		&models.Webhook{},
+		&models.WebhookDelivery{},
+		&models.Notification{},
+		&models.ProcessedWebhookEvent{},
 	)
 
 	if err != nil {
@@ -0,0 +1,95 @@
+package domains
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"io"
+)
+
+// dkimKeyBits is the size of generated DKIM keys. 2048-bit public keys
+// exceed 255 characters, so DNS providers split them into several strings
+// of one TXT record.
+const dkimKeyBits = 2048
+
+// generateDKIMKey returns a new PEM-encoded RSA private key and the base64
+// public key for its DKIM TXT record
+func generateDKIMKey() ([]byte, string, error) {
+	key, err := rsa.GenerateKey(rand.Reader, dkimKeyBits)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to generate DKIM key: %w", err)
+	}
+
+	publicKey, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to encode DKIM public key: %w", err)
+	}
+
+	privateKey := pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(key),
+	})
+
+	return privateKey, base64.StdEncoding.EncodeToString(publicKey), nil
+}
+
+// keyCipher encrypts DKIM private keys at rest with AES-256-GCM
+type keyCipher struct {
+	aead cipher.AEAD
+}
+
+// newKeyCipher accepts a base64-encoded 32-byte key
+func newKeyCipher(encodedKey string) (*keyCipher, error) {
+	key, err := base64.StdEncoding.DecodeString(encodedKey)
+	if err != nil {
+		return nil, fmt.Errorf("key must be base64: %w", err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("key must be 32 bytes, got %d", len(key))
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &keyCipher{aead: aead}, nil
+}
+
+// encrypt returns the base64 nonce and ciphertext
+func (c *keyCipher) encrypt(plaintext []byte) (string, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+
+	sealed := c.aead.Seal(nonce, nonce, plaintext, nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func (c *keyCipher) decrypt(encoded string) ([]byte, error) {
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode DKIM key: %w", err)
+	}
+
+	nonceSize := c.aead.NonceSize()
+	if len(sealed) < nonceSize {
+		return nil, fmt.Errorf("encrypted DKIM key is too short")
+	}
+
+	plaintext, err := c.aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt DKIM key: %w", err)
+	}
+	return plaintext, nil
+}
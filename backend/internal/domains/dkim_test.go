@@ -0,0 +1,194 @@
+package domains
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/shohag/seentics-email/internal/config"
+	"github.com/shohag/seentics-email/internal/models"
+	"github.com/shohag/seentics-email/internal/postal"
+)
+
+const testEncryptionKey = "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=" // 32 bytes
+
+func TestKeyCipherRoundTrip(t *testing.T) {
+	cipher, err := newKeyCipher(testEncryptionKey)
+	if err != nil {
+		t.Fatalf("newKeyCipher: %v", err)
+	}
+
+	encrypted, err := cipher.encrypt([]byte("private key"))
+	if err != nil {
+		t.Fatalf("encrypt: %v", err)
+	}
+	if strings.Contains(encrypted, "private key") {
+		t.Fatal("ciphertext contains the plaintext")
+	}
+
+	decrypted, err := cipher.decrypt(encrypted)
+	if err != nil || string(decrypted) != "private key" {
+		t.Fatalf("decrypt = %q, %v", decrypted, err)
+	}
+
+	other, _ := newKeyCipher(base64.StdEncoding.EncodeToString(make([]byte, 32)))
+	if _, err := other.decrypt(encrypted); err == nil {
+		t.Error("decrypt with another key succeeded")
+	}
+}
+
+func TestNewKeyCipherRejectsBadKeys(t *testing.T) {
+	for _, key := range []string{"not base64!", base64.StdEncoding.EncodeToString(make([]byte, 16))} {
+		if _, err := newKeyCipher(key); err == nil {
+			t.Errorf("newKeyCipher(%q) succeeded", key)
+		}
+	}
+}
+
+// fakePostal records domain registrations and answers with status
+func fakePostal(t *testing.T, status string) (*postal.Client, *[]postal.RegisterDomainRequest) {
+	t.Helper()
+
+	var registered []postal.RegisterDomainRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/domains/create" || r.Header.Get("X-Server-API-Key") != "key" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+
+		var req postal.RegisterDomainRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		registered = append(registered, req)
+
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status": status,
+			"data":   map[string]string{"message": "rejected"},
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	return postal.NewClient(server.URL, "key"), &registered
+}
+
+func testConfig(encryptionKey string) *config.Config {
+	return &config.Config{
+		PostalHostname:       "postal.example.net",
+		PostalReturnPathHost: "rp.example.net",
+		PostalSPFInclude:     "spf.example.net",
+		DKIMSelector:         "s1",
+		DKIMEncryptionKey:    encryptionKey,
+	}
+}
+
+func findRecord(t *testing.T, records []Record, name string) Record {
+	t.Helper()
+
+	for _, record := range records {
+		if record.Name == name {
+			return record
+		}
+	}
+	t.Fatalf("no record named %s in %+v", name, records)
+	return Record{}
+}
+
+func TestProvisionRegistersDKIMKey(t *testing.T) {
+	client, registered := fakePostal(t, "success")
+	provisioner, err := NewProvisioner(testConfig(testEncryptionKey), client)
+	if err != nil {
+		t.Fatalf("NewProvisioner: %v", err)
+	}
+
+	domain := models.Domain{Domain: "example.com"}
+	if err := provisioner.Provision(&domain); err != nil {
+		t.Fatalf("Provision: %v", err)
+	}
+
+	if len(*registered) != 1 {
+		t.Fatalf("registered %d domains with Postal, want 1", len(*registered))
+	}
+	req := (*registered)[0]
+	if req.Name != "example.com" || req.DKIMSelector != "s1" {
+		t.Errorf("registration = %+v", req)
+	}
+
+	// The key at rest is encrypted and decrypts to the registered key
+	if domain.DKIMSelector != "s1" || strings.Contains(domain.DKIMPrivateKey, "PRIVATE KEY") {
+		t.Errorf("stored selector %q, key %q", domain.DKIMSelector, domain.DKIMPrivateKey)
+	}
+	privateKey, err := provisioner.PrivateKey(domain)
+	if err != nil || string(privateKey) != req.DKIMKey {
+		t.Fatalf("PrivateKey = %q, %v; want the registered key", privateKey, err)
+	}
+
+	// The published TXT record carries the registered key's public half
+	block, _ := pem.Decode(privateKey)
+	key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		t.Fatalf("registered key: %v", err)
+	}
+	if key.N.BitLen() != dkimKeyBits {
+		t.Errorf("key size = %d, want %d", key.N.BitLen(), dkimKeyBits)
+	}
+
+	record := findRecord(t, provisioner.Records(domain), "s1._domainkey.example.com")
+	if record.Type != "TXT" || !strings.HasPrefix(record.Value, "v=DKIM1; k=rsa; p=") {
+		t.Fatalf("DKIM record = %+v", record)
+	}
+	der, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(record.Value, "v=DKIM1; k=rsa; p="))
+	published, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		t.Fatalf("published key: %v", err)
+	}
+	if !key.PublicKey.Equal(published.(*rsa.PublicKey)) {
+		t.Error("published key does not match the registered key")
+	}
+}
+
+func TestProvisionFailsWhenPostalRejectsKey(t *testing.T) {
+	client, _ := fakePostal(t, "error")
+	provisioner, _ := NewProvisioner(testConfig(testEncryptionKey), client)
+
+	domain := models.Domain{Domain: "example.com"}
+	if err := provisioner.Provision(&domain); err == nil {
+		t.Fatal("Provision succeeded although Postal rejected the key")
+	}
+	if domain.DNSRecords != "" || domain.DKIMPrivateKey != "" {
+		t.Error("records or key stored for a domain Postal rejected")
+	}
+}
+
+func TestProvisionWithoutEncryptionKeyDelegatesToPostal(t *testing.T) {
+	client, registered := fakePostal(t, "success")
+	provisioner, err := NewProvisioner(testConfig(""), client)
+	if err != nil {
+		t.Fatalf("NewProvisioner: %v", err)
+	}
+
+	domain := models.Domain{Domain: "example.com"}
+	if err := provisioner.Provision(&domain); err != nil {
+		t.Fatalf("Provision: %v", err)
+	}
+
+	if len(*registered) != 0 {
+		t.Errorf("registered %d domains with Postal, want none", len(*registered))
+	}
+	record := findRecord(t, provisioner.Records(domain), "s1._domainkey.example.com")
+	if record.Type != "CNAME" || record.Value != "s1._domainkey.postal.example.net" {
+		t.Errorf("DKIM record = %+v", record)
+	}
+}
+
+func TestRecordsOfLegacyDomain(t *testing.T) {
+	provisioner, _ := NewProvisioner(testConfig(""), nil)
+
+	record := findRecord(t, provisioner.Records(models.Domain{Domain: "example.com"}), "postal._domainkey.example.com")
+	if record.Type != "CNAME" || record.Value != "postal._domainkey.postal.example.net" {
+		t.Errorf("DKIM record = %+v", record)
+	}
+}
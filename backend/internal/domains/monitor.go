@@ -0,0 +1,226 @@
+package domains
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/shohag/seentics-email/internal/database"
+	"github.com/shohag/seentics-email/internal/models"
+	"github.com/shohag/seentics-email/internal/webhooks"
+)
+
+const (
+	// monitorBatchSize is the number of domains handled per query
+	monitorBatchSize = 100
+
+	// monitorTickInterval is how often the monitor looks for due domains.
+	// Each domain is only re-checked once per recheck interval.
+	monitorTickInterval = time.Minute
+
+	// checkTimeout bounds the DNS lookups for one domain
+	checkTimeout = 15 * time.Second
+)
+
+// Monitor re-checks verified domains in the background, marking them degraded
+// when their records drift, and expires domains that stay pending too long
+type Monitor struct {
+	verifier      *Verifier
+	provisioner   *Provisioner
+	dispatcher    *webhooks.Dispatcher
+	interval      time.Duration
+	pendingExpiry time.Duration
+	wg            sync.WaitGroup
+}
+
+func NewMonitor(verifier *Verifier, provisioner *Provisioner, dispatcher *webhooks.Dispatcher, interval, pendingExpiry time.Duration) *Monitor {
+	if interval <= 0 {
+		interval = 6 * time.Hour
+	}
+
+	return &Monitor{
+		verifier:      verifier,
+		provisioner:   provisioner,
+		dispatcher:    dispatcher,
+		interval:      interval,
+		pendingExpiry: pendingExpiry,
+	}
+}
+
+// Start runs the monitor loop until ctx is cancelled
+func (m *Monitor) Start(ctx context.Context) {
+	m.wg.Add(1)
+	go m.run(ctx)
+}
+
+// Wait blocks until the monitor loop has stopped
+func (m *Monitor) Wait() {
+	m.wg.Wait()
+}
+
+func (m *Monitor) run(ctx context.Context) {
+	defer m.wg.Done()
+
+	tick := monitorTickInterval
+	if m.interval < tick {
+		tick = m.interval
+	}
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.recheckDue(ctx)
+			m.expirePending(ctx)
+		}
+	}
+}
+
+// recheckDue re-verifies verified and degraded domains that have not been
+// checked within the interval. Degraded domains are included so that a fix
+// to their records is picked up.
+func (m *Monitor) recheckDue(ctx context.Context) {
+	for ctx.Err() == nil {
+		var due []models.Domain
+		err := database.DB.
+			Where("verification_status IN ?", []models.DomainStatus{models.DomainStatusVerified, models.DomainStatusDegraded}).
+			Where("last_checked_at IS NULL OR last_checked_at <= ?", time.Now().Add(-m.interval)).
+			Order("last_checked_at NULLS FIRST").Limit(monitorBatchSize).Find(&due).Error
+		if err != nil {
+			log.Printf("Failed to load domains for re-verification: %v", err)
+			return
+		}
+
+		for _, domain := range due {
+			if ctx.Err() != nil {
+				return
+			}
+			m.recheck(ctx, domain)
+		}
+
+		if len(due) < monitorBatchSize {
+			return
+		}
+	}
+}
+
+func (m *Monitor) recheck(ctx context.Context, domain models.Domain) {
+	checkCtx, cancel := context.WithTimeout(ctx, checkTimeout)
+	checks, passed := m.verifier.Verify(checkCtx, m.provisioner.Records(domain))
+	cancel()
+
+	status := NextStatus(domain.VerificationStatus, checks, passed)
+
+	checksJSON, err := json.Marshal(checks)
+	if err != nil {
+		log.Printf("Failed to encode checks for domain %s: %v", domain.Domain, err)
+		return
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{
+		"verification_checks": string(checksJSON),
+		"last_checked_at":     now,
+	}
+	if status != domain.VerificationStatus {
+		updates["verification_status"] = status
+		updates["status_changed_at"] = now
+	}
+
+	// Only apply the result if the status was not changed meanwhile, for
+	// example by a manual verification
+	result := database.DB.Model(&models.Domain{}).
+		Where("id = ? AND verification_status = ?", domain.ID, domain.VerificationStatus).
+		Updates(updates)
+	if result.Error != nil {
+		log.Printf("Failed to update domain %s: %v", domain.Domain, result.Error)
+		return
+	}
+
+	if result.RowsAffected > 0 && status != domain.VerificationStatus {
+		log.Printf("Domain %s changed from %s to %s", domain.Domain, domain.VerificationStatus, status)
+		m.notify(domain, status, checks, now)
+	}
+}
+
+// expirePending expires domains that have been pending for longer than the
+// pending expiry
+func (m *Monitor) expirePending(ctx context.Context) {
+	if m.pendingExpiry <= 0 {
+		return
+	}
+
+	for ctx.Err() == nil {
+		var expired []models.Domain
+		err := database.DB.
+			Where("verification_status = ? AND created_at <= ?", models.DomainStatusPending, time.Now().Add(-m.pendingExpiry)).
+			Order("created_at").Limit(monitorBatchSize).Find(&expired).Error
+		if err != nil {
+			log.Printf("Failed to load pending domains: %v", err)
+			return
+		}
+
+		for _, domain := range expired {
+			now := time.Now()
+			result := database.DB.Model(&models.Domain{}).
+				Where("id = ? AND verification_status = ?", domain.ID, models.DomainStatusPending).
+				Updates(map[string]interface{}{
+					"verification_status": models.DomainStatusExpired,
+					"status_changed_at":   now,
+				})
+			if result.Error != nil {
+				log.Printf("Failed to expire domain %s: %v", domain.Domain, result.Error)
+				return
+			}
+			if result.RowsAffected > 0 {
+				m.notify(domain, models.DomainStatusExpired, nil, now)
+			}
+		}
+
+		if len(expired) < monitorBatchSize {
+			return
+		}
+	}
+}
+
+// NextStatus returns a domain's status after a verification. A lookup that
+// could not resolve says nothing about the records, so the status is left
+// alone until the next check. A domain that was verified becomes degraded
+// rather than failed, since its ownership has already been established.
+func NextStatus(current models.DomainStatus, checks []Check, passed bool) models.DomainStatus {
+	switch {
+	case passed:
+		return models.DomainStatusVerified
+	case Unresolved(checks):
+		return current
+	case current == models.DomainStatusVerified || current == models.DomainStatusDegraded:
+		return models.DomainStatusDegraded
+	default:
+		return models.DomainStatusFailed
+	}
+}
+
+// notify sends a domain.verification_changed event to the owner's webhooks
+func (m *Monitor) notify(domain models.Domain, status models.DomainStatus, checks []Check, changedAt time.Time) {
+	if m.dispatcher == nil {
+		return
+	}
+
+	data := map[string]interface{}{
+		"domain_id":       domain.ID,
+		"domain":          domain.Domain,
+		"previous_status": domain.VerificationStatus,
+		"status":          status,
+		"changed_at":      changedAt.UTC(),
+	}
+	if checks != nil {
+		data["checks"] = checks
+	}
+
+	m.dispatcher.Dispatch(domain.UserID, webhooks.EventDomainVerificationChanged, data)
+}
@@ -0,0 +1,165 @@
+// Package domains describes the DNS records a sending domain needs and checks
+// that they are published.
+package domains
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/shohag/seentics-email/internal/config"
+	"github.com/shohag/seentics-email/internal/models"
+	"github.com/shohag/seentics-email/internal/postal"
+)
+
+// Record is a DNS record the domain owner must publish
+type Record struct {
+	Type     string `json:"type"`
+	Name     string `json:"name"`
+	Value    string `json:"value"`
+	Priority int    `json:"priority,omitempty"`
+}
+
+// returnPathPrefix is the label Postal expects the return path CNAME under
+const returnPathPrefix = "psrp."
+
+// legacyDKIMSelector is the selector of domains added before DKIM selectors
+// were configurable
+const legacyDKIMSelector = "postal"
+
+// Provisioner builds the DNS records for domains from the Postal settings in
+// the configuration, generating a DKIM key pair for each new domain and
+// registering it with Postal
+type Provisioner struct {
+	cfg          *config.Config
+	postalClient *postal.Client
+	cipher       *keyCipher
+}
+
+func NewProvisioner(cfg *config.Config, postalClient *postal.Client) (*Provisioner, error) {
+	p := &Provisioner{cfg: cfg, postalClient: postalClient}
+
+	if cfg.DKIMEncryptionKey != "" {
+		cipher, err := newKeyCipher(cfg.DKIMEncryptionKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid DKIM_ENCRYPTION_KEY: %w", err)
+		}
+		p.cipher = cipher
+	}
+
+	if p.cipher == nil {
+		log.Println("Warning: DKIM_ENCRYPTION_KEY is not set, new domains will use Postal's DKIM key instead of their own")
+	}
+
+	return p, nil
+}
+
+// Provision generates the DKIM key pair and DNS records of a new domain and
+// stores them on it, so that later changes to the Postal settings do not
+// change the records of existing domains. The key is registered with Postal,
+// which signs the domain's mail after rewriting it for tracking. Without an
+// encryption key, DKIM is delegated to Postal's key through a CNAME.
+func (p *Provisioner) Provision(domain *models.Domain) error {
+	dkim := p.sharedDKIMRecord(domain.Domain, p.cfg.DKIMSelector)
+
+	if p.cipher != nil {
+		privateKey, publicKey, err := generateDKIMKey()
+		if err != nil {
+			return err
+		}
+
+		encrypted, err := p.cipher.encrypt(privateKey)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt DKIM key: %w", err)
+		}
+
+		err = p.postalClient.RegisterDomain(postal.RegisterDomainRequest{
+			Name:         domain.Domain,
+			DKIMSelector: p.cfg.DKIMSelector,
+			DKIMKey:      string(privateKey),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to register domain with Postal: %w", err)
+		}
+
+		domain.DKIMSelector = p.cfg.DKIMSelector
+		domain.DKIMPrivateKey = encrypted
+		dkim = Record{
+			Type:  "TXT",
+			Name:  p.cfg.DKIMSelector + "._domainkey." + domain.Domain,
+			Value: "v=DKIM1; k=rsa; p=" + publicKey,
+		}
+	}
+
+	records := append(p.baseRecords(domain.Domain), dkim, Record{
+		Type:  "CNAME",
+		Name:  returnPathPrefix + domain.Domain,
+		Value: p.cfg.PostalReturnPathHost,
+	})
+
+	encoded, err := json.Marshal(records)
+	if err != nil {
+		return fmt.Errorf("failed to encode DNS records: %w", err)
+	}
+	domain.DNSRecords = string(encoded)
+
+	return nil
+}
+
+// Records returns the DNS records required for a domain. Domains added
+// before records were stored have none, so theirs are built from the current
+// Postal settings, with the DKIM selector they were added under.
+func (p *Provisioner) Records(domain models.Domain) []Record {
+	var records []Record
+	if domain.DNSRecords != "" {
+		if err := json.Unmarshal([]byte(domain.DNSRecords), &records); err != nil {
+			log.Printf("Failed to decode DNS records of domain %s: %v", domain.Domain, err)
+		}
+	}
+
+	if len(records) == 0 {
+		return append(p.baseRecords(domain.Domain), p.sharedDKIMRecord(domain.Domain, legacyDKIMSelector))
+	}
+	return records
+}
+
+// PrivateKey returns the decrypted PEM-encoded DKIM private key of a domain
+func (p *Provisioner) PrivateKey(domain models.Domain) ([]byte, error) {
+	if domain.DKIMPrivateKey == "" {
+		return nil, fmt.Errorf("domain %s has no DKIM key", domain.Domain)
+	}
+	if p.cipher == nil {
+		return nil, fmt.Errorf("DKIM_ENCRYPTION_KEY is not set")
+	}
+	return p.cipher.decrypt(domain.DKIMPrivateKey)
+}
+
+// sharedDKIMRecord delegates a domain's DKIM selector to Postal's own key
+func (p *Provisioner) sharedDKIMRecord(domain, selector string) Record {
+	return Record{
+		Type:  "CNAME",
+		Name:  selector + "._domainkey." + domain,
+		Value: selector + "._domainkey." + p.cfg.PostalHostname,
+	}
+}
+
+func (p *Provisioner) baseRecords(domain string) []Record {
+	return []Record{
+		{
+			Type:     "MX",
+			Name:     domain,
+			Value:    p.cfg.PostalHostname,
+			Priority: 10,
+		},
+		{
+			Type:  "TXT",
+			Name:  domain,
+			Value: "v=spf1 include:" + p.cfg.PostalSPFInclude + " ~all",
+		},
+		{
+			Type:  "TXT",
+			Name:  "_dmarc." + domain,
+			Value: "v=DMARC1; p=none; rua=mailto:dmarc@" + domain,
+		},
+	}
+}
@@ -0,0 +1,41 @@
+package domains
+
+import (
+	"strings"
+
+	"github.com/shohag/seentics-email/internal/database"
+	"github.com/shohag/seentics-email/internal/models"
+)
+
+// SenderDomain returns the normalized domain of a From address
+func SenderDomain(address string) string {
+	at := strings.LastIndex(address, "@")
+	return strings.TrimSuffix(strings.ToLower(address[at+1:]), ".")
+}
+
+// AuthorizesSender reports whether the domain of a From address is a verified
+// domain of the user. With allowSubdomains, a verified parent domain also
+// authorizes its subdomains. Degraded domains still authorize sending, since
+// their ownership was verified and the owner has been alerted to the drift.
+func AuthorizesSender(userID uint, address string, allowSubdomains bool) (bool, error) {
+	domain := SenderDomain(address)
+
+	candidates := []string{domain}
+	if allowSubdomains {
+		labels := strings.Split(domain, ".")
+		for i := 1; i < len(labels)-1; i++ {
+			candidates = append(candidates, strings.Join(labels[i:], "."))
+		}
+	}
+
+	var count int64
+	err := database.DB.Model(&models.Domain{}).
+		Where("user_id = ? AND LOWER(domain) IN ? AND verification_status IN ?", userID, candidates,
+			[]models.DomainStatus{models.DomainStatusVerified, models.DomainStatusDegraded}).
+		Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
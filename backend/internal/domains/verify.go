@@ -0,0 +1,204 @@
+package domains
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+)
+
+// Resolver looks up DNS records. *net.Resolver satisfies it; tests can
+// substitute an in-process implementation.
+type Resolver interface {
+	LookupMX(ctx context.Context, name string) ([]*net.MX, error)
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+	LookupCNAME(ctx context.Context, host string) (string, error)
+}
+
+// Check is the outcome of looking up one required record
+type Check struct {
+	Record
+	Pass     bool     `json:"pass"`
+	Observed []string `json:"observed"`
+	Error    string   `json:"error,omitempty"`
+
+	// unresolved is set when the lookup itself failed, for example on a
+	// timeout, so the result says nothing about the published records
+	unresolved bool
+}
+
+// Verifier checks required records against DNS
+type Verifier struct {
+	resolver Resolver
+}
+
+// NewVerifier creates a verifier. A nil resolver uses the system resolver.
+func NewVerifier(resolver Resolver) *Verifier {
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	return &Verifier{resolver: resolver}
+}
+
+// Verify looks up every record and reports whether all of them are published
+func (v *Verifier) Verify(ctx context.Context, records []Record) ([]Check, bool) {
+	checks := make([]Check, len(records))
+	passed := true
+	for i, record := range records {
+		checks[i] = v.check(ctx, record)
+		if !checks[i].Pass {
+			passed = false
+		}
+	}
+	return checks, passed
+}
+
+func (v *Verifier) check(ctx context.Context, record Record) Check {
+	check := Check{Record: record, Observed: []string{}}
+
+	switch strings.ToUpper(record.Type) {
+	case "MX":
+		mxs, err := v.resolver.LookupMX(ctx, record.Name)
+		if err != nil {
+			check.Error, check.unresolved = lookupError(err)
+			return check
+		}
+		for _, mx := range mxs {
+			host := normalizeHost(mx.Host)
+			check.Observed = append(check.Observed, host)
+			if host == normalizeHost(record.Value) {
+				check.Pass = true
+			}
+		}
+
+	case "TXT":
+		txts, err := v.resolver.LookupTXT(ctx, record.Name)
+		if err != nil {
+			check.Error, check.unresolved = lookupError(err)
+			return check
+		}
+		checkTXT(&check, txts)
+
+	case "CNAME":
+		target, err := v.resolver.LookupCNAME(ctx, record.Name)
+		if err != nil {
+			check.Error, check.unresolved = lookupError(err)
+			return check
+		}
+		target = normalizeHost(target)
+		check.Observed = append(check.Observed, target)
+		check.Pass = target == normalizeHost(record.Value)
+
+	default:
+		check.Error = "unsupported record type " + record.Type
+	}
+
+	if !check.Pass && check.Error == "" && len(check.Observed) == 0 {
+		check.Error = "no matching record found"
+	}
+	return check
+}
+
+// checkTXT compares published TXT records with the expected one. SPF and DMARC
+// records are matched on their meaning, since owners often merge the SPF
+// include into an existing policy or choose their own DMARC policy. Anything
+// else must match exactly, ignoring whitespace.
+func checkTXT(check *Check, txts []string) {
+	expected := check.Value
+
+	switch {
+	case hasTagPrefix(expected, "v=spf1"):
+		spf := matchingTXT(txts, "v=spf1")
+		check.Observed = spf
+		if len(spf) > 1 {
+			check.Error = "multiple SPF records are published; merge them into one"
+			return
+		}
+		if len(spf) == 1 {
+			published := strings.Fields(strings.ToLower(spf[0]))
+			check.Pass = true
+			for _, term := range strings.Fields(strings.ToLower(expected)) {
+				if strings.HasPrefix(term, "include:") && !containsString(published, term) {
+					check.Pass = false
+				}
+			}
+		}
+
+	case hasTagPrefix(expected, "v=DMARC1"):
+		dmarc := matchingTXT(txts, "v=DMARC1")
+		check.Observed = dmarc
+		if len(dmarc) > 1 {
+			check.Error = "multiple DMARC records are published; remove all but one"
+			return
+		}
+		check.Pass = len(dmarc) == 1
+
+	default:
+		check.Observed = txts
+		for _, txt := range txts {
+			if strings.Join(strings.Fields(txt), "") == strings.Join(strings.Fields(expected), "") {
+				check.Pass = true
+			}
+		}
+	}
+}
+
+// matchingTXT returns the records starting with the given version tag
+func matchingTXT(txts []string, tag string) []string {
+	matches := []string{}
+	for _, txt := range txts {
+		if hasTagPrefix(txt, tag) {
+			matches = append(matches, txt)
+		}
+	}
+	return matches
+}
+
+func hasTagPrefix(txt, tag string) bool {
+	txt = strings.TrimSpace(txt)
+	if len(txt) < len(tag) || !strings.EqualFold(txt[:len(tag)], tag) {
+		return false
+	}
+	rest := txt[len(tag):]
+	return rest == "" || rest[0] == ' ' || rest[0] == ';'
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeHost(host string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(host)), ".")
+}
+
+// lookupError describes a failed lookup and reports whether it was a
+// resolution failure rather than a missing record
+func lookupError(err error) (string, bool) {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		if dnsErr.IsNotFound {
+			return "no records found", false
+		}
+		if dnsErr.IsTimeout {
+			return "DNS lookup timed out", true
+		}
+		return dnsErr.Err, true
+	}
+	return err.Error(), true
+}
+
+// Unresolved reports whether any lookup failed to resolve, in which case a
+// failed verification may only reflect a DNS outage
+func Unresolved(checks []Check) bool {
+	for _, check := range checks {
+		if check.unresolved {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,246 @@
+package domains
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/shohag/seentics-email/internal/models"
+)
+
+// fakeResolver is an in-process DNS stand-in keyed by record name
+type fakeResolver struct {
+	mx    map[string][]*net.MX
+	txt   map[string][]string
+	cname map[string]string
+	err   map[string]error
+}
+
+func (r fakeResolver) LookupMX(ctx context.Context, name string) ([]*net.MX, error) {
+	if err := r.err[name]; err != nil {
+		return nil, err
+	}
+	if mxs, ok := r.mx[name]; ok {
+		return mxs, nil
+	}
+	return nil, notFound(name)
+}
+
+func (r fakeResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	if err := r.err[name]; err != nil {
+		return nil, err
+	}
+	if txts, ok := r.txt[name]; ok {
+		return txts, nil
+	}
+	return nil, notFound(name)
+}
+
+func (r fakeResolver) LookupCNAME(ctx context.Context, name string) (string, error) {
+	if err := r.err[name]; err != nil {
+		return "", err
+	}
+	if target, ok := r.cname[name]; ok {
+		return target, nil
+	}
+	return "", notFound(name)
+}
+
+func notFound(name string) error {
+	return &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+}
+
+func TestVerifierCheck(t *testing.T) {
+	const dkimKey = "MIIBIjANBgkqhkiG9w0BAQEFAAOCAQ8AMIIBCgKCAQEAu5rL"
+
+	tests := []struct {
+		name           string
+		record         Record
+		resolver       fakeResolver
+		wantPass       bool
+		wantError      string
+		wantUnresolved bool
+	}{
+		{
+			name:     "MX matches ignoring case and trailing dot",
+			record:   Record{Type: "MX", Name: "example.com", Value: "postal.example.net", Priority: 10},
+			resolver: fakeResolver{mx: map[string][]*net.MX{"example.com": {{Host: "mx.other.com.", Pref: 5}, {Host: "Postal.Example.NET.", Pref: 10}}}},
+			wantPass: true,
+		},
+		{
+			name:     "MX pointing elsewhere",
+			record:   Record{Type: "MX", Name: "example.com", Value: "postal.example.net"},
+			resolver: fakeResolver{mx: map[string][]*net.MX{"example.com": {{Host: "aspmx.l.google.com.", Pref: 1}}}},
+		},
+		{
+			name:      "MX missing",
+			record:    Record{Type: "MX", Name: "example.com", Value: "postal.example.net"},
+			wantError: "no records found",
+		},
+		{
+			name:     "SPF merged into an existing policy",
+			record:   Record{Type: "TXT", Name: "example.com", Value: "v=spf1 include:spf.postal.example.net ~all"},
+			resolver: fakeResolver{txt: map[string][]string{"example.com": {"google-site-verification=abc", "v=spf1 include:_spf.google.com include:spf.postal.example.net -all"}}},
+			wantPass: true,
+		},
+		{
+			name:     "SPF without the include",
+			record:   Record{Type: "TXT", Name: "example.com", Value: "v=spf1 include:spf.postal.example.net ~all"},
+			resolver: fakeResolver{txt: map[string][]string{"example.com": {"v=spf1 include:_spf.google.com ~all"}}},
+		},
+		{
+			name:   "duplicate SPF records",
+			record: Record{Type: "TXT", Name: "example.com", Value: "v=spf1 include:spf.postal.example.net ~all"},
+			resolver: fakeResolver{txt: map[string][]string{"example.com": {
+				"v=spf1 include:spf.postal.example.net ~all",
+				"v=spf1 include:_spf.google.com ~all",
+			}}},
+			wantError: "multiple SPF records are published; merge them into one",
+		},
+		{
+			name:      "no SPF record among other TXT records",
+			record:    Record{Type: "TXT", Name: "example.com", Value: "v=spf1 include:spf.postal.example.net ~all"},
+			resolver:  fakeResolver{txt: map[string][]string{"example.com": {"v=spf1x not really spf"}}},
+			wantError: "no matching record found",
+		},
+		{
+			name:     "DMARC with a different policy",
+			record:   Record{Type: "TXT", Name: "_dmarc.example.com", Value: "v=DMARC1; p=none; rua=mailto:dmarc@example.com"},
+			resolver: fakeResolver{txt: map[string][]string{"_dmarc.example.com": {"v=DMARC1;p=reject;pct=100"}}},
+			wantPass: true,
+		},
+		{
+			name:      "duplicate DMARC records",
+			record:    Record{Type: "TXT", Name: "_dmarc.example.com", Value: "v=DMARC1; p=none"},
+			resolver:  fakeResolver{txt: map[string][]string{"_dmarc.example.com": {"v=DMARC1; p=none", "v=DMARC1; p=quarantine"}}},
+			wantError: "multiple DMARC records are published; remove all but one",
+		},
+		{
+			// LookupTXT joins the character strings of a record; some
+			// providers add whitespace where they split long keys
+			name:     "DKIM TXT split by the DNS provider",
+			record:   Record{Type: "TXT", Name: "postal._domainkey.example.com", Value: "v=DKIM1; k=rsa; p=" + dkimKey},
+			resolver: fakeResolver{txt: map[string][]string{"postal._domainkey.example.com": {"v=DKIM1; k=rsa; p=MIIBIjANBgkqhkiG9w0BAQEFAAOC AQ8AMIIBCgKCAQEAu5rL"}}},
+			wantPass: true,
+		},
+		{
+			name:     "DKIM TXT with a different key",
+			record:   Record{Type: "TXT", Name: "postal._domainkey.example.com", Value: "v=DKIM1; k=rsa; p=" + dkimKey},
+			resolver: fakeResolver{txt: map[string][]string{"postal._domainkey.example.com": {"v=DKIM1; k=rsa; p=MIGfMA0GCSqGSIb3DQEBAQUAA4GN"}}},
+		},
+		{
+			name:     "CNAME matches",
+			record:   Record{Type: "CNAME", Name: "psrp.example.com", Value: "rp.postal.example.net"},
+			resolver: fakeResolver{cname: map[string]string{"psrp.example.com": "rp.postal.example.net."}},
+			wantPass: true,
+		},
+		{
+			name:     "CNAME pointing elsewhere",
+			record:   Record{Type: "CNAME", Name: "psrp.example.com", Value: "rp.postal.example.net"},
+			resolver: fakeResolver{cname: map[string]string{"psrp.example.com": "psrp.example.com."}},
+		},
+		{
+			name:           "lookup timeout",
+			record:         Record{Type: "TXT", Name: "example.com", Value: "v=spf1 include:spf.postal.example.net ~all"},
+			resolver:       fakeResolver{err: map[string]error{"example.com": &net.DNSError{Err: "i/o timeout", IsTimeout: true}}},
+			wantError:      "DNS lookup timed out",
+			wantUnresolved: true,
+		},
+		{
+			name:           "server failure",
+			record:         Record{Type: "CNAME", Name: "psrp.example.com", Value: "rp.postal.example.net"},
+			resolver:       fakeResolver{err: map[string]error{"psrp.example.com": &net.DNSError{Err: "server misbehaving", IsTemporary: true}}},
+			wantError:      "server misbehaving",
+			wantUnresolved: true,
+		},
+		{
+			name:           "non-DNS error",
+			record:         Record{Type: "MX", Name: "example.com", Value: "postal.example.net"},
+			resolver:       fakeResolver{err: map[string]error{"example.com": errors.New("context canceled")}},
+			wantError:      "context canceled",
+			wantUnresolved: true,
+		},
+		{
+			name:      "unsupported type",
+			record:    Record{Type: "SRV", Name: "_sip._tcp.example.com"},
+			wantError: "unsupported record type SRV",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checks, passed := NewVerifier(tt.resolver).Verify(context.Background(), []Record{tt.record})
+			check := checks[0]
+
+			if check.Pass != tt.wantPass || passed != tt.wantPass {
+				t.Errorf("pass = %v (overall %v), want %v; observed %v", check.Pass, passed, tt.wantPass, check.Observed)
+			}
+			if check.Error != tt.wantError {
+				t.Errorf("error = %q, want %q", check.Error, tt.wantError)
+			}
+			if got := Unresolved(checks); got != tt.wantUnresolved {
+				t.Errorf("Unresolved = %v, want %v", got, tt.wantUnresolved)
+			}
+			if check.Observed == nil {
+				t.Error("observed should never be nil")
+			}
+		})
+	}
+}
+
+func TestVerifyAllRecords(t *testing.T) {
+	records := []Record{
+		{Type: "MX", Name: "example.com", Value: "postal.example.net", Priority: 10},
+		{Type: "TXT", Name: "example.com", Value: "v=spf1 include:postal.example.net ~all"},
+		{Type: "TXT", Name: "_dmarc.example.com", Value: "v=DMARC1; p=none"},
+		{Type: "CNAME", Name: "postal._domainkey.example.com", Value: "postal._domainkey.postal.example.net"},
+	}
+
+	resolver := fakeResolver{
+		mx:    map[string][]*net.MX{"example.com": {{Host: "postal.example.net.", Pref: 10}}},
+		txt:   map[string][]string{"example.com": {"v=spf1 include:postal.example.net ~all"}, "_dmarc.example.com": {"v=DMARC1; p=none"}},
+		cname: map[string]string{"postal._domainkey.example.com": "postal._domainkey.postal.example.net."},
+	}
+
+	checks, passed := NewVerifier(resolver).Verify(context.Background(), records)
+	if !passed {
+		t.Fatalf("expected all records to pass: %+v", checks)
+	}
+
+	delete(resolver.cname, "postal._domainkey.example.com")
+	checks, passed = NewVerifier(resolver).Verify(context.Background(), records)
+	if passed {
+		t.Fatal("expected verification to fail without the DKIM record")
+	}
+	if !checks[0].Pass || checks[3].Pass || Unresolved(checks) {
+		t.Errorf("unexpected checks: %+v", checks)
+	}
+}
+
+func TestNextStatus(t *testing.T) {
+	timedOut := []Check{{unresolved: true}}
+	missing := []Check{{Error: "no records found"}}
+
+	tests := []struct {
+		current models.DomainStatus
+		checks  []Check
+		passed  bool
+		want    models.DomainStatus
+	}{
+		{models.DomainStatusPending, nil, true, models.DomainStatusVerified},
+		{models.DomainStatusDegraded, nil, true, models.DomainStatusVerified},
+		{models.DomainStatusPending, missing, false, models.DomainStatusFailed},
+		{models.DomainStatusExpired, missing, false, models.DomainStatusFailed},
+		{models.DomainStatusVerified, missing, false, models.DomainStatusDegraded},
+		{models.DomainStatusDegraded, missing, false, models.DomainStatusDegraded},
+		{models.DomainStatusVerified, timedOut, false, models.DomainStatusVerified},
+		{models.DomainStatusPending, timedOut, false, models.DomainStatusPending},
+	}
+
+	for _, tt := range tests {
+		if got := NextStatus(tt.current, tt.checks, tt.passed); got != tt.want {
+			t.Errorf("NextStatus(%s, passed=%v) = %s, want %s", tt.current, tt.passed, got, tt.want)
+		}
+	}
+}
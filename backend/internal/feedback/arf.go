@@ -0,0 +1,119 @@
+package feedback
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// HeaderMessageID is added to every outgoing email so that feedback reports,
+// which quote the original headers, can be matched back to the email
+const HeaderMessageID = "X-Seentics-Message-ID"
+
+// ErrNotFeedbackReport is returned for messages that are not ARF reports
+var ErrNotFeedbackReport = errors.New("message is not an ARF feedback report")
+
+// Report is an Abuse Reporting Format (RFC 5965) feedback report
+type Report struct {
+	FeedbackType     string    // e.g. abuse, fraud, virus
+	UserAgent        string    // The mailbox provider that generated the report
+	OriginalRcptTo   string    // The complaining recipient, if not redacted
+	OriginalMailFrom string    // Envelope sender of the original message
+	ArrivalDate      time.Time // When the original message arrived, if known
+
+	// From the quoted headers of the original message
+	MessageID         string // Our X-Seentics-Message-ID
+	OriginalMessageID string // The Message-ID header
+	OriginalTo        string
+}
+
+// Recipient returns the complaining recipient's address, or "" if the
+// provider left it out. The quoted To header is not used, since CC and BCC
+// recipients receive the same header. Some providers replace the address with
+// a placeholder, so the result may not match any recipient.
+func (r *Report) Recipient() string {
+	if address, err := mail.ParseAddress(r.OriginalRcptTo); err == nil {
+		return address.Address
+	}
+	return ""
+}
+
+// Parse reads a raw RFC 5322 message and returns its feedback report
+func Parse(raw []byte) (*Report, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read message: %w", err)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/report" || !strings.EqualFold(params["report-type"], "feedback-report") {
+		return nil, ErrNotFeedbackReport
+	}
+
+	report := &Report{}
+	foundReport := false
+
+	reader := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read report part: %w", err)
+		}
+
+		partType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		body, err := io.ReadAll(io.LimitReader(part, 1<<20))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read report part: %w", err)
+		}
+
+		switch partType {
+		case "message/feedback-report":
+			fields, err := readHeaderBlock(body)
+			if err != nil {
+				return nil, fmt.Errorf("invalid feedback report: %w", err)
+			}
+			report.FeedbackType = strings.ToLower(fields.Get("Feedback-Type"))
+			report.UserAgent = fields.Get("User-Agent")
+			report.OriginalRcptTo = fields.Get("Original-Rcpt-To")
+			report.OriginalMailFrom = fields.Get("Original-Mail-From")
+			if date, err := mail.ParseDate(fields.Get("Arrival-Date")); err == nil {
+				report.ArrivalDate = date
+			}
+			foundReport = true
+
+		case "message/rfc822", "text/rfc822-headers":
+			// The original message, or only its headers
+			headers, err := readHeaderBlock(body)
+			if err != nil {
+				continue
+			}
+			report.MessageID = strings.TrimSpace(headers.Get(HeaderMessageID))
+			report.OriginalMessageID = strings.Trim(headers.Get("Message-Id"), " <>")
+			report.OriginalTo = headers.Get("To")
+		}
+	}
+
+	if !foundReport {
+		return nil, ErrNotFeedbackReport
+	}
+
+	return report, nil
+}
+
+// readHeaderBlock parses RFC 5322 style fields up to the first blank line
+func readHeaderBlock(body []byte) (textproto.MIMEHeader, error) {
+	// Make sure the block is terminated even if the part only holds headers
+	block := append(bytes.TrimLeft(body, "\r\n"), "\r\n\r\n"...)
+	return textproto.NewReader(bufio.NewReader(bytes.NewReader(block))).ReadMIMEHeader()
+}
@@ -0,0 +1,91 @@
+package feedback
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func readSample(t *testing.T, name string) []byte {
+	t.Helper()
+	raw, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		sample        string
+		want          Report
+		wantRecipient string
+	}{
+		{
+			sample: "abuse.eml",
+			want: Report{
+				FeedbackType:      "abuse",
+				UserAgent:         "Yahoo!-Mail-Feedback/2.0",
+				OriginalRcptTo:    "<Jane.Doe@yahoo.com>",
+				OriginalMailFrom:  "<bounce+a1b2c3@psrp.example.com>",
+				ArrivalDate:       time.Date(2026, 10, 6, 14, 18, 52, 0, time.UTC),
+				MessageID:         "0b5d8c1e-6a1f-4f1e-8a3c-7c2f1d9e4b10",
+				OriginalMessageID: "a1b2c3d4@postal.example.net",
+				OriginalTo:        "Jane Doe <jane.doe@yahoo.com>",
+			},
+			wantRecipient: "Jane.Doe@yahoo.com",
+		},
+		{
+			// Microsoft leaves out Original-Rcpt-To and quotes only the
+			// headers, with the To address replaced
+			sample: "redacted.eml",
+			want: Report{
+				FeedbackType:      "abuse",
+				UserAgent:         "Microsoft JMRP/1.0",
+				OriginalMailFrom:  "<bounce+d4e5f6@psrp.example.com>",
+				ArrivalDate:       time.Date(2026, 10, 7, 15, 59, 30, 0, time.UTC),
+				MessageID:         "9e3f1b2a-4c5d-4e6f-8a7b-1c2d3e4f5a6b",
+				OriginalMessageID: "d4e5f6a7@postal.example.net",
+				OriginalTo:        "<redacted@hotmail.com>",
+			},
+			wantRecipient: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sample, func(t *testing.T) {
+			report, err := Parse(readSample(t, tt.sample))
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+
+			if !report.ArrivalDate.Equal(tt.want.ArrivalDate) {
+				t.Errorf("ArrivalDate = %v, want %v", report.ArrivalDate, tt.want.ArrivalDate)
+			}
+			got := *report
+			got.ArrivalDate = tt.want.ArrivalDate
+			if got != tt.want {
+				t.Errorf("report:\n got %+v\nwant %+v", got, tt.want)
+			}
+
+			if recipient := report.Recipient(); recipient != tt.wantRecipient {
+				t.Errorf("Recipient = %q, want %q", recipient, tt.wantRecipient)
+			}
+		})
+	}
+}
+
+func TestParseRejectsOtherMessages(t *testing.T) {
+	samples := map[string][]byte{
+		"delivery status notification": readSample(t, "delivery-status.eml"),
+		"plain message":                []byte("From: a@example.com\r\nTo: b@example.com\r\nSubject: hi\r\n\r\nhello\r\n"),
+	}
+
+	for name, raw := range samples {
+		if _, err := Parse(raw); !errors.Is(err, ErrNotFeedbackReport) {
+			t.Errorf("%s: err = %v, want ErrNotFeedbackReport", name, err)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/shohag/seentics-email/internal/postal"
+)
+
+type AttachmentRequest struct {
+	Name        string `json:"name" binding:"required"`
+	ContentType string `json:"content_type" binding:"required"`
+	Data        string `json:"data" binding:"required"` // Base64 encoded
+	ContentID   string `json:"content_id"`              // Set to embed an image referenced as cid:<content_id>
+}
+
+// attachmentInfo is the attachment summary recorded on each email log
+type attachmentInfo struct {
+	Name        string `json:"name"`
+	ContentType string `json:"content_type"`
+	Size        int    `json:"size"`
+	Inline      bool   `json:"inline,omitempty"`
+}
+
+// prepareAttachments validates attachments against the configured size limits
+// and content-type allow list, and returns them in Postal's format along with
+// the JSON summary stored on the email logs
+func (h *EmailHandler) prepareAttachments(attachments []AttachmentRequest) ([]postal.Attachment, string, *sendError) {
+	prepared := make([]postal.Attachment, 0, len(attachments))
+	infos := make([]attachmentInfo, 0, len(attachments))
+	total := 0
+
+	for _, attachment := range attachments {
+		if strings.ContainsAny(attachment.Name, "\r\n\"") {
+			return nil, "", &sendError{Status: http.StatusBadRequest, Message: fmt.Sprintf("Invalid attachment name %q", attachment.Name)}
+		}
+
+		contentType, _, err := mime.ParseMediaType(attachment.ContentType)
+		if err != nil {
+			return nil, "", &sendError{Status: http.StatusBadRequest, Message: fmt.Sprintf("Invalid content type for attachment %s", attachment.Name)}
+		}
+		if !h.attachmentTypeAllowed(contentType) {
+			return nil, "", &sendError{Status: http.StatusUnprocessableEntity, Message: fmt.Sprintf("Content type %s is not allowed for attachment %s", contentType, attachment.Name)}
+		}
+
+		if attachment.ContentID != "" {
+			if !strings.HasPrefix(contentType, "image/") {
+				return nil, "", &sendError{Status: http.StatusBadRequest, Message: fmt.Sprintf("Inline attachment %s must be an image", attachment.Name)}
+			}
+			if strings.ContainsAny(attachment.ContentID, " <>\r\n\"") {
+				return nil, "", &sendError{Status: http.StatusBadRequest, Message: fmt.Sprintf("Invalid content_id for attachment %s", attachment.Name)}
+			}
+		}
+
+		data, err := base64.StdEncoding.DecodeString(attachment.Data)
+		if err != nil {
+			return nil, "", &sendError{Status: http.StatusBadRequest, Message: fmt.Sprintf("Attachment %s is not valid base64", attachment.Name)}
+		}
+
+		if len(data) > h.cfg.AttachmentMaxBytes {
+			return nil, "", &sendError{Status: http.StatusRequestEntityTooLarge, Message: fmt.Sprintf("Attachment %s exceeds the %d byte limit", attachment.Name, h.cfg.AttachmentMaxBytes)}
+		}
+		total += len(data)
+		if total > h.cfg.AttachmentMaxTotalBytes {
+			return nil, "", &sendError{Status: http.StatusRequestEntityTooLarge, Message: fmt.Sprintf("Attachments exceed the %d byte total limit", h.cfg.AttachmentMaxTotalBytes)}
+		}
+
+		prepared = append(prepared, postal.Attachment{
+			Name:        attachment.Name,
+			ContentType: contentType,
+			Data:        attachment.Data,
+			ContentID:   attachment.ContentID,
+		})
+		infos = append(infos, attachmentInfo{
+			Name:        attachment.Name,
+			ContentType: contentType,
+			Size:        len(data),
+			Inline:      attachment.ContentID != "",
+		})
+	}
+
+	summary, err := json.Marshal(infos)
+	if err != nil {
+		return nil, "", &sendError{Status: http.StatusInternalServerError, Message: "Failed to record attachments"}
+	}
+
+	return prepared, string(summary), nil
+}
+
+// attachmentTypeAllowed checks a media type against the allow list, which may
+// contain wildcard subtypes such as image/*
+func (h *EmailHandler) attachmentTypeAllowed(contentType string) bool {
+	for _, allowed := range h.cfg.AttachmentAllowedTypes {
+		allowed = strings.ToLower(strings.TrimSpace(allowed))
+		if allowed == contentType {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(allowed, "/*"); ok && strings.HasPrefix(contentType, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
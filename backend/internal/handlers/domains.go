@@ -1,17 +1,31 @@
 package handlers
 
 import (
+	"context"
+	"encoding/json"
+	"log"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/shohag/seentics-email/internal/database"
+	"github.com/shohag/seentics-email/internal/domains"
 	"github.com/shohag/seentics-email/internal/models"
 )
 
-type DomainHandler struct{}
+// domainVerifyTimeout bounds the DNS lookups of a verification request
+const domainVerifyTimeout = 15 * time.Second
 
-func NewDomainHandler() *DomainHandler {
-	return &DomainHandler{}
+type DomainHandler struct {
+	verifier    *domains.Verifier
+	provisioner *domains.Provisioner
+}
+
+func NewDomainHandler(verifier *domains.Verifier, provisioner *domains.Provisioner) *DomainHandler {
+	return &DomainHandler{
+		verifier:    verifier,
+		provisioner: provisioner,
+	}
 }
 
 type AddDomainRequest struct {
@@ -22,17 +36,10 @@ type DomainResponse struct {
 	ID                 uint                `json:"id"`
 	Domain             string              `json:"domain"`
 	VerificationStatus models.DomainStatus `json:"verification_status"`
-	DNSRecords         []DNSRecord         `json:"dns_records"`
+	DNSRecords         []domains.Record    `json:"dns_records"`
 	CreatedAt          string              `json:"created_at"`
 }
 
-type DNSRecord struct {
-	Type     string `json:"type"`
-	Name     string `json:"name"`
-	Value    string `json:"value"`
-	Priority int    `json:"priority,omitempty"`
-}
-
 // ListDomains returns all domains for the authenticated user
 func (h *DomainHandler) ListDomains(c *gin.Context) {
 	userID := c.GetUint("userID")
@@ -49,7 +56,7 @@ func (h *DomainHandler) ListDomains(c *gin.Context) {
 			ID:                 domain.ID,
 			Domain:             domain.Domain,
 			VerificationStatus: domain.VerificationStatus,
-			DNSRecords:         generateDNSRecords(domain.Domain),
+			DNSRecords:         h.provisioner.Records(domain),
 			CreatedAt:          domain.CreatedAt.Format("2006-01-02T15:04:05Z"),
 		}
 	}
@@ -67,11 +74,27 @@ func (h *DomainHandler) AddDomain(c *gin.Context) {
 		return
 	}
 
-	// Check if domain already exists
+	// Check if domain already exists. Deleted and expired domains are taken
+	// over, since the domain name is unique across all accounts.
 	var existing models.Domain
-	if err := database.DB.Where("domain = ?", req.Domain).First(&existing).Error; err == nil {
-		c.JSON(http.StatusConflict, gin.H{"error": "Domain already exists"})
-		return
+	if err := database.DB.Unscoped().Where("domain = ?", req.Domain).First(&existing).Error; err == nil {
+		if !claimableDomain(existing) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Domain already exists"})
+			return
+		}
+
+		// Re-check the status in the delete, in case the domain was verified meanwhile
+		result := database.DB.Unscoped().
+			Where("id = ? AND (deleted_at IS NOT NULL OR verification_status = ?)", existing.ID, models.DomainStatusExpired).
+			Delete(&models.Domain{})
+		if result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add domain"})
+			return
+		}
+		if result.RowsAffected == 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "Domain already exists"})
+			return
+		}
 	}
 
 	domain := models.Domain{
@@ -80,6 +103,13 @@ func (h *DomainHandler) AddDomain(c *gin.Context) {
 		VerificationStatus: models.DomainStatusPending,
 	}
 
+	// Provisioning also registers the domain's DKIM key with Postal
+	if err := h.provisioner.Provision(&domain); err != nil {
+		log.Printf("Failed to provision domain %s: %v", domain.Domain, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set up domain"})
+		return
+	}
+
 	if err := database.DB.Create(&domain).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add domain"})
 		return
@@ -89,11 +119,17 @@ func (h *DomainHandler) AddDomain(c *gin.Context) {
 		ID:                 domain.ID,
 		Domain:             domain.Domain,
 		VerificationStatus: domain.VerificationStatus,
-		DNSRecords:         generateDNSRecords(domain.Domain),
+		DNSRecords:         h.provisioner.Records(domain),
 		CreatedAt:          domain.CreatedAt.Format("2006-01-02T15:04:05Z"),
 	})
 }
 
+// claimableDomain reports whether an existing domain may be replaced by a
+// new one with the same name
+func claimableDomain(domain models.Domain) bool {
+	return domain.DeletedAt.Valid || domain.VerificationStatus == models.DomainStatusExpired
+}
+
 // GetDomainVerification returns DNS records needed for verification
 func (h *DomainHandler) GetDomainVerification(c *gin.Context) {
 	userID := c.GetUint("userID")
@@ -105,10 +141,17 @@ func (h *DomainHandler) GetDomainVerification(c *gin.Context) {
 		return
 	}
 
+	checks := []domains.Check{}
+	if domain.VerificationChecks != "" {
+		json.Unmarshal([]byte(domain.VerificationChecks), &checks)
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"domain":      domain.Domain,
-		"dns_records": generateDNSRecords(domain.Domain),
-		"status":      domain.VerificationStatus,
+		"domain":          domain.Domain,
+		"dns_records":     h.provisioner.Records(domain),
+		"status":          domain.VerificationStatus,
+		"checks":          checks,
+		"last_checked_at": domain.LastCheckedAt,
 	})
 }
 
@@ -142,38 +185,37 @@ func (h *DomainHandler) VerifyDomain(c *gin.Context) {
 		return
 	}
 
-	// TODO: Implement actual DNS verification logic
-	// For now, just return the current status
-	c.JSON(http.StatusOK, gin.H{
-		"domain":  domain.Domain,
-		"status":  domain.VerificationStatus,
-		"message": "DNS verification is not yet implemented. Please verify manually in Postal.",
-	})
-}
+	ctx, cancel := context.WithTimeout(c.Request.Context(), domainVerifyTimeout)
+	defer cancel()
+
+	checks, passed := h.verifier.Verify(ctx, h.provisioner.Records(domain))
+	status := domains.NextStatus(domain.VerificationStatus, checks, passed)
+
+	checksJSON, err := json.Marshal(checks)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encode verification results"})
+		return
+	}
 
-// generateDNSRecords generates the required DNS records for a domain
-func generateDNSRecords(domain string) []DNSRecord {
-	return []DNSRecord{
-		{
-			Type:     "MX",
-			Name:     domain,
-			Value:    "postal.yourdomain.com",
-			Priority: 10,
-		},
-		{
-			Type:  "TXT",
-			Name:  domain,
-			Value: "v=spf1 include:postal.yourdomain.com ~all",
-		},
-		{
-			Type:  "TXT",
-			Name:  "_dmarc." + domain,
-			Value: "v=DMARC1; p=none; rua=mailto:dmarc@" + domain,
-		},
-		{
-			Type:  "CNAME",
-			Name:  "postal._domainkey." + domain,
-			Value: "postal._domainkey.postal.yourdomain.com",
-		},
+	now := time.Now()
+	updates := map[string]interface{}{
+		"verification_status": status,
+		"verification_checks": string(checksJSON),
+		"last_checked_at":     now,
 	}
+	if status != domain.VerificationStatus {
+		updates["status_changed_at"] = now
+	}
+
+	if err := database.DB.Model(&domain).Updates(updates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update domain"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"domain":          domain.Domain,
+		"status":          status,
+		"checks":          checks,
+		"last_checked_at": now.Format("2006-01-02T15:04:05Z"),
+	})
 }
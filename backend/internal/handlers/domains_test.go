@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shohag/seentics-email/internal/models"
+	"gorm.io/gorm"
+)
+
+func TestClaimableDomain(t *testing.T) {
+	deleted := gorm.DeletedAt{Time: time.Now(), Valid: true}
+
+	tests := []struct {
+		name   string
+		domain models.Domain
+		want   bool
+	}{
+		{"pending", models.Domain{VerificationStatus: models.DomainStatusPending}, false},
+		{"verified", models.Domain{VerificationStatus: models.DomainStatusVerified}, false},
+		{"degraded", models.Domain{VerificationStatus: models.DomainStatusDegraded}, false},
+		{"expired", models.Domain{VerificationStatus: models.DomainStatusExpired}, true},
+		{"deleted", models.Domain{VerificationStatus: models.DomainStatusVerified, DeletedAt: deleted}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := claimableDomain(tt.domain); got != tt.want {
+				t.Errorf("claimableDomain() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,105 +1,64 @@
 package handlers
 
 import (
-	"fmt"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
+	"github.com/shohag/seentics-email/internal/config"
 	"github.com/shohag/seentics-email/internal/database"
+	"github.com/shohag/seentics-email/internal/mailer"
 	"github.com/shohag/seentics-email/internal/models"
 	"github.com/shohag/seentics-email/internal/postal"
 )
 
 type EmailHandler struct {
+	cfg          *config.Config
 	postalClient *postal.Client
+	mailer       *mailer.Mailer
 }
 
-func NewEmailHandler(postalClient *postal.Client) *EmailHandler {
+func NewEmailHandler(cfg *config.Config, postalClient *postal.Client, mailer *mailer.Mailer) *EmailHandler {
 	return &EmailHandler{
+		cfg:          cfg,
 		postalClient: postalClient,
+		mailer:       mailer,
 	}
 }
 
-type SendEmailRequest struct {
-	To        []string          `json:"to" binding:"required"`
-	From      string            `json:"from" binding:"required,email"`
-	Subject   string            `json:"subject" binding:"required"`
-	HTMLBody  string            `json:"html_body"`
-	PlainBody string            `json:"plain_body"`
-	Headers   map[string]string `json:"headers"`
-}
-
-type SendEmailResponse struct {
-	MessageID       string `json:"message_id"`
-	PostalMessageID string `json:"postal_message_id"`
-	Status          string `json:"status"`
-}
-
-// SendEmail sends an email via Postal
-func (h *EmailHandler) SendEmail(c *gin.Context) {
+// CancelScheduledEmail cancels a scheduled email before it is sent.
+// The id may be an email log ID or the message_id returned by /api/send.
+func (h *EmailHandler) CancelScheduledEmail(c *gin.Context) {
 	userID := c.GetUint("userID")
+	emailID := c.Param("id")
 
-	var req SendEmailRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
+	query := database.DB.Where("user_id = ?", userID)
+	if _, err := strconv.ParseUint(emailID, 10, 64); err == nil {
+		query = query.Where("id = ?", emailID)
+	} else {
+		query = query.Where("message_id = ?", emailID)
 	}
 
-	// Validate that at least one body is provided
-	if req.HTMLBody == "" && req.PlainBody == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Either html_body or plain_body is required"})
+	var email models.EmailLog
+	if err := query.First(&email).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Email not found"})
 		return
 	}
 
-	// Generate unique message ID
-	messageID := uuid.New().String()
-
-	// Send via Postal
-	postalReq := postal.SendEmailRequest{
-		To:        req.To,
-		From:      req.From,
-		Subject:   req.Subject,
-		HTMLBody:  req.HTMLBody,
-		PlainBody: req.PlainBody,
-		Headers:   req.Headers,
-	}
-
-	postalResp, err := h.postalClient.SendEmail(postalReq)
+	cancelled, err := h.mailer.Cancel(email.MessageID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to send email: %v", err)})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel email"})
 		return
 	}
 
-	// Extract Postal message ID
-	var postalMessageID string
-	if postalResp.Data.MessageID != "" {
-		postalMessageID = postalResp.Data.MessageID
-	}
-
-	// Log email in database
-	for _, recipient := range req.To {
-		emailLog := models.EmailLog{
-			UserID:          userID,
-			MessageID:       messageID,
-			PostalMessageID: postalMessageID,
-			From:            req.From,
-			To:              recipient,
-			Subject:         req.Subject,
-			Status:          models.EmailStatusSent,
-		}
-
-		if err := database.DB.Create(&emailLog).Error; err != nil {
-			// Log error but don't fail the request
-			fmt.Printf("Failed to log email: %v\n", err)
-		}
+	if !cancelled {
+		c.JSON(http.StatusConflict, gin.H{"error": "Email is not scheduled or has already been sent"})
+		return
 	}
 
-	c.JSON(http.StatusOK, SendEmailResponse{
-		MessageID:       messageID,
-		PostalMessageID: postalMessageID,
-		Status:          "sent",
+	c.JSON(http.StatusOK, gin.H{
+		"message_id": email.MessageID,
+		"status":     models.EmailStatusCancelled,
 	})
 }
 
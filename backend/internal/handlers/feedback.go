@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shohag/seentics-email/internal/database"
+	"github.com/shohag/seentics-email/internal/feedback"
+	"github.com/shohag/seentics-email/internal/models"
+	"github.com/shohag/seentics-email/internal/postal"
+	"github.com/shohag/seentics-email/internal/webhooks"
+)
+
+// maxFeedbackReportBytes caps the size of an inbound feedback report
+const maxFeedbackReportBytes = 10 << 20
+
+// HandleFeedbackReport receives ARF (RFC 5965) complaint reports that Postal
+// routes from the feedback address to this endpoint. The complaint is matched
+// to the recipient's email log through the X-Seentics-Message-ID header
+// quoted in the report, and the recipient is suppressed.
+func (h *WebhookHandler) HandleFeedbackReport(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	if h.cfg.FeedbackToken == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Feedback ingestion is not configured"})
+		return
+	}
+
+	token := c.GetHeader("X-Feedback-Token")
+	if token == "" {
+		token = c.Query("token")
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(h.cfg.FeedbackToken)) != 1 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid feedback token"})
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxFeedbackReportBytes))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+		return
+	}
+
+	inbound, err := postal.ParseInboundMessage(body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid inbound message payload"})
+		return
+	}
+
+	if h.cfg.FeedbackAddress != "" && !strings.EqualFold(inbound.RcptTo, h.cfg.FeedbackAddress) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Message was not sent to the feedback address"})
+		return
+	}
+
+	raw, err := inbound.Raw()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid raw message encoding"})
+		return
+	}
+
+	report, err := feedback.Parse(raw)
+	if errors.Is(err, feedback.ErrNotFeedbackReport) {
+		c.JSON(http.StatusOK, gin.H{"message": "Not a feedback report"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid feedback report"})
+		return
+	}
+
+	// Postal retries deliveries to endpoints, so skip reports already processed
+	eventKey := ""
+	if key := inbound.Key(); key != "" {
+		eventKey = "inbound:" + key
+		firstSeen, err := h.markEventProcessed(ctx, eventKey)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record feedback report"})
+			return
+		}
+		if !firstSeen {
+			c.JSON(http.StatusOK, gin.H{"message": "Duplicate report ignored"})
+			return
+		}
+	}
+
+	emailLog, err := findComplaintEmailLog(report)
+	if err != nil {
+		log.Printf("Feedback report not matched (message %q, recipient %q): %v", report.MessageID, report.Recipient(), err)
+		c.JSON(http.StatusOK, gin.H{"message": "Email log not found"})
+		return
+	}
+
+	occurredAt := report.ArrivalDate
+	if occurredAt.IsZero() {
+		occurredAt = time.Now()
+	}
+
+	if err := applyEmailEvent(emailLog.ID, models.EmailStatusComplaint, "complained_at", occurredAt); err != nil {
+		if eventKey != "" {
+			h.unmarkEventProcessed(ctx, eventKey)
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update email log"})
+		return
+	}
+
+	details := "Feedback-Type: " + report.FeedbackType
+	if report.UserAgent != "" {
+		details += " from " + report.UserAgent
+	}
+	suppressRecipient(emailLog, models.SuppressionReasonComplaint, details)
+
+	h.dispatcher.Dispatch(emailLog.UserID, webhooks.EventMessageComplaint, gin.H{
+		"email_id":          emailLog.ID,
+		"message_id":        emailLog.MessageID,
+		"postal_message_id": emailLog.PostalMessageID,
+		"from":              emailLog.From,
+		"to":                emailLog.To,
+		"subject":           emailLog.Subject,
+		"occurred_at":       occurredAt.UTC(),
+		"status":            models.EmailStatusComplaint,
+		"feedback_type":     report.FeedbackType,
+		"user_agent":        report.UserAgent,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Feedback report processed"})
+}
+
+// findComplaintEmailLog matches a feedback report to a recipient's email log
+func findComplaintEmailLog(report *feedback.Report) (*models.EmailLog, error) {
+	if report.MessageID == "" {
+		return nil, errors.New("report does not quote the X-Seentics-Message-ID header")
+	}
+
+	var emailLogs []models.EmailLog
+	if err := database.DB.Where("message_id = ?", report.MessageID).Find(&emailLogs).Error; err != nil {
+		return nil, err
+	}
+
+	return matchComplaint(report, emailLogs)
+}
+
+// matchComplaint picks the email log a report is about from the logs of the
+// reported message. Providers often redact the recipient or replace it with a
+// placeholder; the report can then only be matched if the original message
+// had a single recipient.
+func matchComplaint(report *feedback.Report, emailLogs []models.EmailLog) (*models.EmailLog, error) {
+	if recipient := report.Recipient(); recipient != "" {
+		for i := range emailLogs {
+			if strings.EqualFold(emailLogs[i].To, recipient) {
+				return &emailLogs[i], nil
+			}
+		}
+	}
+
+	switch len(emailLogs) {
+	case 0:
+		return nil, errors.New("no email log for the reported message")
+	case 1:
+		return &emailLogs[0], nil
+	default:
+		return nil, errors.New("recipient is redacted and the message had several recipients")
+	}
+}
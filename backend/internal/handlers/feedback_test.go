@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/shohag/seentics-email/internal/feedback"
+	"github.com/shohag/seentics-email/internal/models"
+)
+
+func TestMatchComplaint(t *testing.T) {
+	single := []models.EmailLog{{ID: 1, To: "jane.doe@yahoo.com"}}
+	several := []models.EmailLog{
+		{ID: 1, To: "alice@example.com", RecipientType: models.RecipientTypeTo},
+		{ID: 2, To: "bob@example.com", RecipientType: models.RecipientTypeCC},
+	}
+
+	tests := []struct {
+		name      string
+		report    feedback.Report
+		emailLogs []models.EmailLog
+		wantID    uint
+		wantErr   bool
+	}{
+		{
+			name:      "recipient matched ignoring case",
+			report:    feedback.Report{OriginalRcptTo: "<Jane.Doe@yahoo.com>"},
+			emailLogs: single,
+			wantID:    1,
+		},
+		{
+			name:      "recipient picks one of several",
+			report:    feedback.Report{OriginalRcptTo: "bob@example.com"},
+			emailLogs: several,
+			wantID:    2,
+		},
+		{
+			name:      "redacted recipient with a single recipient",
+			report:    feedback.Report{OriginalTo: "<redacted@hotmail.com>"},
+			emailLogs: single,
+			wantID:    1,
+		},
+		{
+			name:      "placeholder recipient with a single recipient",
+			report:    feedback.Report{OriginalRcptTo: "redacted@yahoo.com"},
+			emailLogs: single,
+			wantID:    1,
+		},
+		{
+			// The To header is shared with CC recipients, so it must not
+			// attribute the complaint
+			name:      "redacted recipient with several recipients",
+			report:    feedback.Report{OriginalTo: "alice@example.com"},
+			emailLogs: several,
+			wantErr:   true,
+		},
+		{
+			name:    "no email logs",
+			report:  feedback.Report{OriginalRcptTo: "jane.doe@yahoo.com"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			emailLog, err := matchComplaint(&tt.report, tt.emailLogs)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, matched email log %d", emailLog.ID)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("matchComplaint: %v", err)
+			}
+			if emailLog.ID != tt.wantID {
+				t.Errorf("matched email log %d, want %d", emailLog.ID, tt.wantID)
+			}
+		})
+	}
+}
@@ -0,0 +1,570 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/google/uuid"
+	"github.com/shohag/seentics-email/internal/database"
+	"github.com/shohag/seentics-email/internal/domains"
+	"github.com/shohag/seentics-email/internal/feedback"
+	"github.com/shohag/seentics-email/internal/htmlbody"
+	"github.com/shohag/seentics-email/internal/mailer"
+	"github.com/shohag/seentics-email/internal/models"
+	"github.com/shohag/seentics-email/internal/postal"
+	"github.com/shohag/seentics-email/internal/suppressions"
+	"github.com/shohag/seentics-email/internal/templates"
+	"gorm.io/gorm"
+)
+
+type SendEmailRequest struct {
+	To          []string            `json:"to" binding:"required"`
+	CC          []string            `json:"cc"`
+	BCC         []string            `json:"bcc"`
+	From        string              `json:"from" binding:"required"` // May include a display name
+	ReplyTo     string              `json:"reply_to"`
+	Subject     string              `json:"subject"` // Required unless template_id is set
+	HTMLBody    string              `json:"html_body"`
+	PlainBody   string              `json:"plain_body"`
+	Headers     map[string]string   `json:"headers"`
+	Attachments []AttachmentRequest `json:"attachments" binding:"dive"`
+	SendAt      *time.Time          `json:"send_at"` // RFC3339; omit to send immediately
+
+	// A plain-text part is generated from html_body when plain_body is empty,
+	// unless auto_plain_body is false
+	AutoPlainBody *bool `json:"auto_plain_body"`
+
+	// Optional pre-send processing of html_body
+	InlineCSS    bool `json:"inline_css"`
+	SanitizeHTML bool `json:"sanitize_html"`
+
+	// A stored template replaces subject, html_body and plain_body
+	TemplateID *uint                  `json:"template_id"`
+	Variables  map[string]interface{} `json:"variables"`
+}
+
+type SendEmailResponse struct {
+	MessageID       string            `json:"message_id"`
+	PostalMessageID string            `json:"postal_message_id"`
+	Status          string            `json:"status"`
+	SendAt          *time.Time        `json:"send_at,omitempty"`
+	Recipients      []RecipientResult `json:"recipients,omitempty"`
+	Suppressed      []string          `json:"suppressed,omitempty"` // Recipients not sent to
+	Warnings        []string          `json:"warnings,omitempty"`
+}
+
+// RecipientResult carries the Postal identifiers assigned to one recipient
+type RecipientResult struct {
+	To              string               `json:"to"`
+	RecipientType   models.RecipientType `json:"recipient_type"`
+	PostalMessageID string               `json:"postal_message_id"`
+	PostalToken     string               `json:"postal_token"`
+}
+
+type SendBatchRequest struct {
+	Messages []json.RawMessage `json:"messages" binding:"required"`
+}
+
+// BatchItemResult reports the outcome of one message of a batch
+type BatchItemResult struct {
+	Index int `json:"index"`
+	*SendEmailResponse
+	Error string `json:"error,omitempty"`
+}
+
+// sendError is a failure to accept a message, with the HTTP status to report
+type sendError struct {
+	Status  int
+	Message string
+}
+
+func (e *sendError) Error() string {
+	return e.Message
+}
+
+// SendEmail records an email as queued and hands it to the mailer.
+// With a send queue it returns 202 immediately; otherwise it sends inline.
+func (h *EmailHandler) SendEmail(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	var req SendEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	status, response, sendErr := h.sendMessage(c.Request.Context(), userID, &req)
+	if sendErr != nil {
+		c.JSON(sendErr.Status, gin.H{"error": sendErr.Message})
+		return
+	}
+
+	c.JSON(status, response)
+}
+
+// SendBatch accepts many independent messages in one call. Each message is
+// validated and sent on its own and gets its own result.
+func (h *EmailHandler) SendBatch(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	var req SendBatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if len(req.Messages) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "At least one message is required"})
+		return
+	}
+
+	if len(req.Messages) > h.cfg.BatchMaxMessages {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("A batch may contain at most %d messages", h.cfg.BatchMaxMessages)})
+		return
+	}
+
+	results := make([]BatchItemResult, len(req.Messages))
+	failed := 0
+
+	for i, raw := range req.Messages {
+		results[i].Index = i
+
+		var item SendEmailRequest
+		if err := json.Unmarshal(raw, &item); err != nil {
+			results[i].Error = "Invalid message: " + err.Error()
+			failed++
+			continue
+		}
+		if err := binding.Validator.ValidateStruct(&item); err != nil {
+			results[i].Error = err.Error()
+			failed++
+			continue
+		}
+
+		_, response, sendErr := h.sendMessage(c.Request.Context(), userID, &item)
+		if sendErr != nil {
+			results[i].Error = sendErr.Message
+			failed++
+			continue
+		}
+		results[i].SendEmailResponse = response
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"results":  results,
+		"accepted": len(results) - failed,
+		"failed":   failed,
+	})
+}
+
+// sendMessage validates a request, logs it and schedules, queues or sends it.
+// It returns the HTTP status and response for a successful hand-off.
+func (h *EmailHandler) sendMessage(ctx context.Context, userID uint, req *SendEmailRequest) (int, *SendEmailResponse, *sendError) {
+	prepared, sendErr := h.prepareMessage(userID, req)
+	if sendErr != nil {
+		return 0, nil, sendErr
+	}
+	msg := prepared.Message
+
+	// Messages with a future send time wait for the scheduler
+	scheduled := req.SendAt != nil && req.SendAt.After(time.Now())
+	status := models.EmailStatusQueued
+	if scheduled {
+		status = models.EmailStatusScheduled
+	}
+
+	// Log email in database before handing it off, so it is never lost.
+	// Every recipient, including CC and BCC, gets its own row.
+	emailLogs := make([]models.EmailLog, 0, len(prepared.Recipients))
+	for _, recipient := range prepared.Recipients {
+		emailLogs = append(emailLogs, models.EmailLog{
+			UserID:        userID,
+			MessageID:     msg.MessageID,
+			From:          prepared.From,
+			To:            recipient.Address,
+			RecipientType: recipient.Type,
+			Subject:       msg.Request.Subject,
+			Status:        status,
+			Attachments:   prepared.Attachments,
+			TemplateID:    prepared.TemplateID,
+		})
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&emailLogs).Error; err != nil {
+			return err
+		}
+		if scheduled {
+			return h.mailer.Schedule(tx, msg, *req.SendAt)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, nil, &sendError{Status: http.StatusInternalServerError, Message: "Failed to log email"}
+	}
+
+	if scheduled {
+		sendAt := req.SendAt.UTC()
+		return http.StatusAccepted, &SendEmailResponse{
+			MessageID:  msg.MessageID,
+			Status:     string(models.EmailStatusScheduled),
+			SendAt:     &sendAt,
+			Suppressed: prepared.Suppressed,
+			Warnings:   prepared.Warnings,
+		}, nil
+	}
+
+	postalResp, err := h.mailer.Submit(ctx, msg)
+	if err != nil {
+		return 0, nil, &sendError{Status: http.StatusInternalServerError, Message: fmt.Sprintf("Failed to send email: %v", err)}
+	}
+
+	if postalResp == nil {
+		return http.StatusAccepted, &SendEmailResponse{
+			MessageID:  msg.MessageID,
+			Status:     string(models.EmailStatusQueued),
+			Suppressed: prepared.Suppressed,
+			Warnings:   prepared.Warnings,
+		}, nil
+	}
+
+	return http.StatusOK, &SendEmailResponse{
+		MessageID:       msg.MessageID,
+		PostalMessageID: postalResp.Data.MessageID,
+		Status:          string(models.EmailStatusSent),
+		Recipients:      recipientResults(prepared.Recipients, postalResp),
+		Suppressed:      prepared.Suppressed,
+		Warnings:        prepared.Warnings,
+	}, nil
+}
+
+// preparedMessage is a validated outgoing message plus what is recorded on its email logs
+type preparedMessage struct {
+	Message     *mailer.Message
+	From        string      // Bare sender address
+	Recipients  []recipient // Unique recipients across to, cc and bcc
+	Attachments string      // JSON attachment summary
+	TemplateID  *uint
+	Warnings    []string // Non-fatal problems reported in the response
+	Suppressed  []string // Recipients dropped because they are suppressed
+}
+
+// recipient is a bare recipient address and how it was addressed
+type recipient struct {
+	Address string
+	Type    models.RecipientType
+}
+
+// prepareMessage validates a send request and builds the outgoing message
+func (h *EmailHandler) prepareMessage(userID uint, req *SendEmailRequest) (*preparedMessage, *sendError) {
+	content, err := resolveContent(userID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var warnings []string
+	if content.HTMLBody != "" {
+		htmlBody, pipelineWarnings, pipelineErr := htmlbody.Prepare(content.HTMLBody, htmlbody.Options{
+			InlineCSS: req.InlineCSS,
+			Sanitize:  req.SanitizeHTML,
+		})
+		if pipelineErr != nil {
+			return nil, &sendError{Status: http.StatusBadRequest, Message: pipelineErr.Error()}
+		}
+		content.HTMLBody = htmlBody
+		warnings = append(warnings, pipelineWarnings...)
+
+		if warning := htmlbody.SizeWarning(content.HTMLBody); warning != "" {
+			warnings = append(warnings, warning)
+		}
+	}
+
+	if content.PlainBody == "" && content.HTMLBody != "" && (req.AutoPlainBody == nil || *req.AutoPlainBody) {
+		content.PlainBody = htmlbody.ToText(content.HTMLBody)
+	}
+
+	if len(req.To) == 0 {
+		return nil, &sendError{Status: http.StatusBadRequest, Message: "At least one recipient is required"}
+	}
+
+	from, err := parseAddress("from", req.From)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorizeSender(userID, from.Address, h.cfg.AllowSenderSubdomains); err != nil {
+		return nil, err
+	}
+
+	var replyTo string
+	if req.ReplyTo != "" {
+		address, err := parseAddress("reply_to", req.ReplyTo)
+		if err != nil {
+			return nil, err
+		}
+		replyTo = address.String()
+	}
+
+	to, err := parseAddressList("to", req.To)
+	if err != nil {
+		return nil, err
+	}
+	cc, err := parseAddressList("cc", req.CC)
+	if err != nil {
+		return nil, err
+	}
+	bcc, err := parseAddressList("bcc", req.BCC)
+	if err != nil {
+		return nil, err
+	}
+
+	// Suppressed recipients are dropped and reported instead of sent to
+	to, cc, bcc, suppressed, err := dropSuppressed(userID, to, cc, bcc)
+	if err != nil {
+		return nil, err
+	}
+	if len(to)+len(cc)+len(bcc) == 0 {
+		return nil, &sendError{Status: http.StatusUnprocessableEntity, Message: "All recipients are suppressed: " + strings.Join(suppressed, ", ")}
+	}
+
+	attachments, summary, err := h.prepareAttachments(req.Attachments)
+	if err != nil {
+		return nil, err
+	}
+
+	// Tag the message so feedback reports can be matched back to it
+	messageID := uuid.New().String()
+	headers := make(map[string]string, len(req.Headers)+1)
+	for name, value := range req.Headers {
+		if reservedHeader(name) {
+			return nil, &sendError{Status: http.StatusBadRequest, Message: fmt.Sprintf("Header %s cannot be set through headers", name)}
+		}
+		if !strings.EqualFold(name, feedback.HeaderMessageID) {
+			headers[name] = value
+		}
+	}
+	headers[feedback.HeaderMessageID] = messageID
+
+	return &preparedMessage{
+		Message: &mailer.Message{
+			MessageID: messageID,
+			UserID:    userID,
+			Request: postal.SendEmailRequest{
+				To:          formatAddresses(to),
+				CC:          formatAddresses(cc),
+				BCC:         formatAddresses(bcc),
+				From:        from.String(),
+				ReplyTo:     replyTo,
+				Subject:     content.Subject,
+				HTMLBody:    content.HTMLBody,
+				PlainBody:   content.PlainBody,
+				Headers:     headers,
+				Attachments: attachments,
+			},
+		},
+		From:        from.Address,
+		Recipients:  uniqueRecipients(to, cc, bcc),
+		Attachments: summary,
+		TemplateID:  req.TemplateID,
+		Warnings:    warnings,
+		Suppressed:  suppressed,
+	}, nil
+}
+
+// dropSuppressed removes suppressed addresses from the recipient lists and
+// returns them separately
+func dropSuppressed(userID uint, to, cc, bcc []*mail.Address) ([]*mail.Address, []*mail.Address, []*mail.Address, []string, *sendError) {
+	var all []string
+	for _, list := range [][]*mail.Address{to, cc, bcc} {
+		for _, address := range list {
+			all = append(all, address.Address)
+		}
+	}
+
+	suppressedSet, err := suppressions.Suppressed(userID, all)
+	if err != nil {
+		return nil, nil, nil, nil, &sendError{Status: http.StatusInternalServerError, Message: "Failed to check suppressions"}
+	}
+	if len(suppressedSet) == 0 {
+		return to, cc, bcc, nil, nil
+	}
+
+	var suppressed []string
+	reported := make(map[string]bool)
+	filter := func(list []*mail.Address) []*mail.Address {
+		kept := list[:0:0]
+		for _, address := range list {
+			key := suppressions.Normalize(address.Address)
+			if !suppressedSet[key] {
+				kept = append(kept, address)
+				continue
+			}
+			if !reported[key] {
+				reported[key] = true
+				suppressed = append(suppressed, address.Address)
+			}
+		}
+		return kept
+	}
+
+	return filter(to), filter(cc), filter(bcc), suppressed, nil
+}
+
+// resolveContent returns the subject and bodies of a request, rendering its
+// template if it has one. Rendering errors, such as a missing variable, are
+// reported before anything is sent.
+func resolveContent(userID uint, req *SendEmailRequest) (*templates.Content, *sendError) {
+	if req.TemplateID == nil {
+		if req.Subject == "" {
+			return nil, &sendError{Status: http.StatusBadRequest, Message: "subject is required"}
+		}
+		// Validate that at least one body is provided
+		if req.HTMLBody == "" && req.PlainBody == "" {
+			return nil, &sendError{Status: http.StatusBadRequest, Message: "Either html_body or plain_body is required"}
+		}
+		return &templates.Content{Subject: req.Subject, HTMLBody: req.HTMLBody, PlainBody: req.PlainBody}, nil
+	}
+
+	if req.Subject != "" || req.HTMLBody != "" || req.PlainBody != "" {
+		return nil, &sendError{Status: http.StatusBadRequest, Message: "template_id cannot be combined with subject, html_body or plain_body"}
+	}
+
+	_, version, err := loadTemplateVersion(userID, *req.TemplateID, 0)
+	if err != nil {
+		return nil, &sendError{Status: http.StatusNotFound, Message: "Template not found"}
+	}
+
+	content, err := templates.Render(templates.Content{
+		Subject:   version.Subject,
+		HTMLBody:  version.HTMLBody,
+		PlainBody: version.PlainBody,
+	}, req.Variables)
+	if err != nil {
+		return nil, &sendError{Status: http.StatusUnprocessableEntity, Message: "Failed to render template: " + err.Error()}
+	}
+
+	return content, nil
+}
+
+// parseAddress parses an address with an optional display name, such as
+// "Acme Billing <billing@acme.com>"
+func parseAddress(field, value string) (*mail.Address, *sendError) {
+	address, err := mail.ParseAddress(value)
+	if err != nil {
+		return nil, &sendError{Status: http.StatusBadRequest, Message: fmt.Sprintf("Invalid %s address %q", field, value)}
+	}
+	return address, nil
+}
+
+// reservedHeaders are set from the request fields and may not be overridden
+// through custom headers, which would bypass sender authorization
+var reservedHeaders = map[string]bool{
+	"from":         true,
+	"sender":       true,
+	"to":           true,
+	"cc":           true,
+	"bcc":          true,
+	"reply-to":     true,
+	"subject":      true,
+	"message-id":   true,
+	"date":         true,
+	"mime-version": true,
+}
+
+// reservedHeader reports whether a custom header name is reserved. All
+// Content-* headers are reserved since they describe the MIME structure.
+func reservedHeader(name string) bool {
+	name = strings.ToLower(strings.TrimSpace(name))
+	return reservedHeaders[name] || strings.HasPrefix(name, "content-")
+}
+
+// authorizeSender checks that the domain of a From address is a verified
+// domain of the user
+func authorizeSender(userID uint, address string, allowSubdomains bool) *sendError {
+	authorized, err := domains.AuthorizesSender(userID, address, allowSubdomains)
+	if err != nil {
+		return &sendError{Status: http.StatusInternalServerError, Message: "Failed to check sender domain"}
+	}
+
+	if !authorized {
+		return &sendError{Status: http.StatusForbidden, Message: fmt.Sprintf("Sender domain %s is not a verified domain of this account", domains.SenderDomain(address))}
+	}
+	return nil
+}
+
+// parseAddressList parses each address of a recipient list
+func parseAddressList(field string, values []string) ([]*mail.Address, *sendError) {
+	addresses := make([]*mail.Address, 0, len(values))
+	for _, value := range values {
+		address, err := parseAddress(field, value)
+		if err != nil {
+			return nil, err
+		}
+		addresses = append(addresses, address)
+	}
+	return addresses, nil
+}
+
+// formatAddresses renders addresses for Postal, encoding display names as needed
+func formatAddresses(addresses []*mail.Address) []string {
+	if len(addresses) == 0 {
+		return nil
+	}
+	formatted := make([]string, 0, len(addresses))
+	for _, address := range addresses {
+		formatted = append(formatted, address.String())
+	}
+	return formatted
+}
+
+// uniqueRecipients lists every recipient once. Postal sends a single copy to
+// an address that appears more than once, so the first occurrence wins.
+func uniqueRecipients(to, cc, bcc []*mail.Address) []recipient {
+	seen := make(map[string]bool)
+	var recipients []recipient
+
+	add := func(addresses []*mail.Address, recipientType models.RecipientType) {
+		for _, address := range addresses {
+			key := strings.ToLower(address.Address)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			recipients = append(recipients, recipient{Address: address.Address, Type: recipientType})
+		}
+	}
+
+	add(to, models.RecipientTypeTo)
+	add(cc, models.RecipientTypeCC)
+	add(bcc, models.RecipientTypeBCC)
+
+	return recipients
+}
+
+// recipientResults pairs each recipient with the Postal IDs it was assigned
+func recipientResults(recipients []recipient, postalResp *postal.SendEmailResponse) []RecipientResult {
+	// Postal keys its per-recipient messages by address
+	recipientMessages := make(map[string]postal.MessageInfo, len(postalResp.Data.Messages))
+	for address, info := range postalResp.Data.Messages {
+		recipientMessages[strings.ToLower(address)] = info
+	}
+
+	results := make([]RecipientResult, 0, len(recipients))
+	for _, recipient := range recipients {
+		result := RecipientResult{To: recipient.Address, RecipientType: recipient.Type}
+		if info, ok := recipientMessages[strings.ToLower(recipient.Address)]; ok {
+			result.PostalMessageID = strconv.Itoa(info.ID)
+			result.PostalToken = info.Token
+		}
+		results = append(results, result)
+	}
+
+	return results
+}
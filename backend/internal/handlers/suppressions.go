@@ -0,0 +1,322 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/mail"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shohag/seentics-email/internal/database"
+	"github.com/shohag/seentics-email/internal/models"
+	"github.com/shohag/seentics-email/internal/suppressions"
+	"gorm.io/gorm/clause"
+)
+
+// maxSuppressionImportBytes caps the size of an uploaded suppression CSV
+const maxSuppressionImportBytes = 10 << 20
+
+type SuppressionHandler struct{}
+
+func NewSuppressionHandler() *SuppressionHandler {
+	return &SuppressionHandler{}
+}
+
+type CreateSuppressionRequest struct {
+	Email   string `json:"email" binding:"required,email"`
+	Reason  string `json:"reason"` // bounce, complaint or manual (default)
+	Details string `json:"details"`
+}
+
+// ImportError reports a CSV line that could not be imported
+type ImportError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+// ListSuppressions returns paginated suppressions for the authenticated user
+func (h *SuppressionHandler) ListSuppressions(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	// Pagination parameters
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if limit > 100 {
+		limit = 100
+	}
+	offset := (page - 1) * limit
+
+	// Filter parameters
+	query := database.DB.Where("user_id = ?", userID)
+	if email := c.Query("email"); email != "" {
+		query = query.Where("email LIKE ?", "%"+suppressions.Normalize(email)+"%")
+	}
+	if reason := c.Query("reason"); reason != "" {
+		query = query.Where("reason = ?", reason)
+	}
+
+	// Get total count
+	var total int64
+	query.Model(&models.Suppression{}).Count(&total)
+
+	var list []models.Suppression
+	if err := query.Order("created_at DESC").Limit(limit).Offset(offset).Find(&list).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch suppressions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"suppressions": list,
+		"pagination": gin.H{
+			"page":  page,
+			"limit": limit,
+			"total": total,
+		},
+	})
+}
+
+// CreateSuppression suppresses an address manually
+func (h *SuppressionHandler) CreateSuppression(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	var req CreateSuppressionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	reason, err := parseSuppressionReason(req.Reason)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	created, err := suppressions.Add(userID, req.Email, reason, req.Details, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create suppression"})
+		return
+	}
+	if !created {
+		c.JSON(http.StatusConflict, gin.H{"error": "Address is already suppressed"})
+		return
+	}
+
+	var suppression models.Suppression
+	database.DB.Where("user_id = ? AND email = ?", userID, suppressions.Normalize(req.Email)).First(&suppression)
+
+	c.JSON(http.StatusCreated, suppression)
+}
+
+// DeleteSuppression removes a suppression so the address can be sent to again
+func (h *SuppressionHandler) DeleteSuppression(c *gin.Context) {
+	userID := c.GetUint("userID")
+	suppressionID := c.Param("id")
+
+	result := database.DB.Where("id = ? AND user_id = ?", suppressionID, userID).Delete(&models.Suppression{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete suppression"})
+		return
+	}
+
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Suppression not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Suppression deleted successfully"})
+}
+
+// ExportSuppressions streams all suppressions as CSV
+func (h *SuppressionHandler) ExportSuppressions(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	rows, err := database.DB.Model(&models.Suppression{}).Where("user_id = ?", userID).Order("email").Rows()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export suppressions"})
+		return
+	}
+	defer rows.Close()
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", `attachment; filename="suppressions.csv"`)
+	c.Status(http.StatusOK)
+
+	writer := csv.NewWriter(c.Writer)
+	writer.Write([]string{"email", "reason", "details", "created_at"})
+	for rows.Next() {
+		var suppression models.Suppression
+		if err := database.DB.ScanRows(rows, &suppression); err != nil {
+			break
+		}
+		writer.Write([]string{
+			csvSafe(suppression.Email),
+			string(suppression.Reason),
+			csvSafe(suppression.Details),
+			suppression.CreatedAt.Format("2006-01-02T15:04:05Z"),
+		})
+	}
+	writer.Flush()
+}
+
+// ImportSuppressions adds suppressions from a CSV upload, sent either as the
+// "file" field of a multipart form or as a text/csv request body. The CSV
+// needs an email column; reason and details columns are optional. Addresses
+// that are already suppressed are skipped.
+func (h *SuppressionHandler) ImportSuppressions(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSuppressionImportBytes)
+
+	var source io.Reader = c.Request.Body
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		file, err := c.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "A CSV file is required in the file field"})
+			return
+		}
+		opened, err := file.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read CSV file"})
+			return
+		}
+		defer opened.Close()
+		source = opened
+	}
+
+	reader := csv.NewReader(source)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read CSV header"})
+		return
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	emailColumn, ok := columns["email"]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "CSV must have an email column"})
+		return
+	}
+
+	var batch []models.Suppression
+	var importErrors []ImportError
+	seen := make(map[string]bool)
+	line := 1
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line++
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("CSV may be at most %d bytes", maxSuppressionImportBytes)})
+				return
+			}
+			importErrors = append(importErrors, ImportError{Line: line, Error: err.Error()})
+			continue
+		}
+
+		address, err := mail.ParseAddress(csvField(record, emailColumn))
+		if err != nil {
+			importErrors = append(importErrors, ImportError{Line: line, Error: "invalid email address"})
+			continue
+		}
+
+		reason, err := parseSuppressionReason(csvField(record, columnIndex(columns, "reason")))
+		if err != nil {
+			importErrors = append(importErrors, ImportError{Line: line, Error: err.Error()})
+			continue
+		}
+
+		email := suppressions.Normalize(address.Address)
+		if seen[email] {
+			continue
+		}
+		seen[email] = true
+
+		batch = append(batch, models.Suppression{
+			UserID:  userID,
+			Email:   email,
+			Reason:  reason,
+			Details: csvField(record, columnIndex(columns, "details")),
+		})
+	}
+
+	var imported int64
+	if len(batch) > 0 {
+		result := database.DB.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(&batch, 500)
+		if result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import suppressions"})
+			return
+		}
+		imported = result.RowsAffected
+	}
+
+	if importErrors == nil {
+		importErrors = []ImportError{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"imported": imported,
+		"skipped":  int64(len(batch)) - imported,
+		"errors":   importErrors,
+	})
+}
+
+// parseSuppressionReason validates a reason, defaulting to manual
+func parseSuppressionReason(value string) (models.SuppressionReason, error) {
+	switch reason := models.SuppressionReason(strings.ToLower(strings.TrimSpace(value))); reason {
+	case "":
+		return models.SuppressionReasonManual, nil
+	case models.SuppressionReasonBounce, models.SuppressionReasonComplaint, models.SuppressionReasonManual:
+		return reason, nil
+	default:
+		return "", fmt.Errorf("invalid reason %q", value)
+	}
+}
+
+func columnIndex(columns map[string]int, name string) int {
+	if i, ok := columns[name]; ok {
+		return i
+	}
+	return -1
+}
+
+func csvField(record []string, i int) string {
+	if i < 0 || i >= len(record) {
+		return ""
+	}
+	return csvUnescape(strings.TrimSpace(record[i]))
+}
+
+// csvFormulaPrefixes start cells that spreadsheets evaluate as formulas
+const csvFormulaPrefixes = "=+-@\t\r"
+
+// csvSafe prefixes cells that would be evaluated as formulas with a quote.
+// Details hold bounce diagnostics written by remote mail servers.
+func csvSafe(value string) string {
+	if value != "" && strings.ContainsRune(csvFormulaPrefixes, rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// csvUnescape reverses csvSafe, so that exports can be imported again
+func csvUnescape(value string) string {
+	if len(value) > 1 && value[0] == '\'' && strings.ContainsRune(csvFormulaPrefixes, rune(value[1])) {
+		return value[1:]
+	}
+	return value
+}
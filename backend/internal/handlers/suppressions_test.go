@@ -0,0 +1,30 @@
+package handlers
+
+import "testing"
+
+func TestCSVSafe(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"", ""},
+		{"user@example.com", "user@example.com"},
+		{"550 5.1.1 User unknown", "550 5.1.1 User unknown"},
+		{`=HYPERLINK("http://evil.example","click")`, `'=HYPERLINK("http://evil.example","click")`},
+		{"+1+cmd|' /C calc'!A0", "'+1+cmd|' /C calc'!A0"},
+		{"-2+3", "'-2+3"},
+		{"@SUM(A1:A2)", "'@SUM(A1:A2)"},
+		{"\t=1", "'\t=1"},
+		{"it's fine", "it's fine"},
+	}
+
+	for _, tt := range tests {
+		got := csvSafe(tt.value)
+		if got != tt.want {
+			t.Errorf("csvSafe(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+		if back := csvUnescape(got); back != tt.value {
+			t.Errorf("csvUnescape(%q) = %q, want %q", got, back, tt.value)
+		}
+	}
+}
@@ -0,0 +1,487 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shohag/seentics-email/internal/config"
+	"github.com/shohag/seentics-email/internal/database"
+	"github.com/shohag/seentics-email/internal/htmlbody"
+	"github.com/shohag/seentics-email/internal/models"
+	"github.com/shohag/seentics-email/internal/postal"
+	"github.com/shohag/seentics-email/internal/templates"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type TemplateHandler struct {
+	cfg          *config.Config
+	postalClient *postal.Client
+}
+
+func NewTemplateHandler(cfg *config.Config, postalClient *postal.Client) *TemplateHandler {
+	return &TemplateHandler{
+		cfg:          cfg,
+		postalClient: postalClient,
+	}
+}
+
+type CreateTemplateRequest struct {
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+	Subject     string `json:"subject" binding:"required"`
+	HTMLBody    string `json:"html_body"`
+	PlainBody   string `json:"plain_body"`
+}
+
+// UpdateTemplateRequest changes a template. Changing the subject or a body
+// creates a new version; omitted parts are copied from the current version.
+type UpdateTemplateRequest struct {
+	Name        *string `json:"name"`
+	Description *string `json:"description"`
+	Subject     *string `json:"subject"`
+	HTMLBody    *string `json:"html_body"`
+	PlainBody   *string `json:"plain_body"`
+}
+
+type PreviewTemplateRequest struct {
+	Version   int                    `json:"version"` // Defaults to the current version
+	Variables map[string]interface{} `json:"variables"`
+}
+
+type TestSendTemplateRequest struct {
+	To        string                 `json:"to" binding:"required"`
+	From      string                 `json:"from" binding:"required"`
+	Version   int                    `json:"version"` // Defaults to the current version
+	Variables map[string]interface{} `json:"variables"`
+}
+
+type PreviewTemplateResponse struct {
+	Version   int      `json:"version"`
+	Subject   string   `json:"subject"`
+	HTMLBody  string   `json:"html_body"`
+	PlainBody string   `json:"plain_body"`
+	Warnings  []string `json:"warnings"`
+}
+
+type TemplateResponse struct {
+	ID          uint   `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Version     int    `json:"version"`
+	Subject     string `json:"subject"`
+	HTMLBody    string `json:"html_body"`
+	PlainBody   string `json:"plain_body"`
+	CreatedAt   string `json:"created_at"`
+	UpdatedAt   string `json:"updated_at"`
+}
+
+type TemplateVersionResponse struct {
+	Version   int    `json:"version"`
+	Subject   string `json:"subject"`
+	HTMLBody  string `json:"html_body"`
+	PlainBody string `json:"plain_body"`
+	CreatedAt string `json:"created_at"`
+}
+
+// ListTemplates returns all templates for the authenticated user
+func (h *TemplateHandler) ListTemplates(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	var tmpls []models.Template
+	if err := database.DB.Where("user_id = ?", userID).Order("name").Find(&tmpls).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch templates"})
+		return
+	}
+
+	response := make([]TemplateResponse, 0, len(tmpls))
+	for _, tmpl := range tmpls {
+		var version models.TemplateVersion
+		if err := database.DB.Where("template_id = ? AND version = ?", tmpl.ID, tmpl.CurrentVersion).First(&version).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch templates"})
+			return
+		}
+		response = append(response, toTemplateResponse(tmpl, version))
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// CreateTemplate creates a template with its first version
+func (h *TemplateHandler) CreateTemplate(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	var req CreateTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	content := templates.Content{Subject: req.Subject, HTMLBody: req.HTMLBody, PlainBody: req.PlainBody}
+	if err := validateTemplateContent(content); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tmpl := models.Template{
+		UserID:         userID,
+		Name:           req.Name,
+		Description:    req.Description,
+		CurrentVersion: 1,
+	}
+	version := models.TemplateVersion{
+		Version:   1,
+		Subject:   req.Subject,
+		HTMLBody:  req.HTMLBody,
+		PlainBody: req.PlainBody,
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&tmpl).Error; err != nil {
+			return err
+		}
+		version.TemplateID = tmpl.ID
+		return tx.Create(&version).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create template"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, toTemplateResponse(tmpl, version))
+}
+
+// GetTemplate returns a template with the content of its current version
+func (h *TemplateHandler) GetTemplate(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	tmpl, version, err := loadTemplateVersion(userID, c.Param("id"), 0)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Template not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, toTemplateResponse(*tmpl, *version))
+}
+
+// UpdateTemplate renames a template or saves new content as a new version
+func (h *TemplateHandler) UpdateTemplate(c *gin.Context) {
+	userID := c.GetUint("userID")
+	templateID := c.Param("id")
+
+	var req UpdateTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var tmpl models.Template
+	var version models.TemplateVersion
+	var validationErr error
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		// Lock the template so concurrent edits get distinct version numbers
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND user_id = ?", templateID, userID).First(&tmpl).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("template_id = ? AND version = ?", tmpl.ID, tmpl.CurrentVersion).First(&version).Error; err != nil {
+			return err
+		}
+
+		updates := map[string]interface{}{}
+		if req.Name != nil {
+			if *req.Name == "" {
+				validationErr = errors.New("name cannot be empty")
+				return validationErr
+			}
+			updates["name"] = *req.Name
+		}
+		if req.Description != nil {
+			updates["description"] = *req.Description
+		}
+
+		if req.Subject != nil || req.HTMLBody != nil || req.PlainBody != nil {
+			next := models.TemplateVersion{
+				TemplateID: tmpl.ID,
+				Version:    tmpl.CurrentVersion + 1,
+				Subject:    stringOr(req.Subject, version.Subject),
+				HTMLBody:   stringOr(req.HTMLBody, version.HTMLBody),
+				PlainBody:  stringOr(req.PlainBody, version.PlainBody),
+			}
+
+			content := templates.Content{Subject: next.Subject, HTMLBody: next.HTMLBody, PlainBody: next.PlainBody}
+			if validationErr = validateTemplateContent(content); validationErr != nil {
+				return validationErr
+			}
+
+			if err := tx.Create(&next).Error; err != nil {
+				return err
+			}
+			version = next
+			updates["current_version"] = next.Version
+		}
+
+		if len(updates) == 0 {
+			return nil
+		}
+		return tx.Model(&tmpl).Updates(updates).Error
+	})
+
+	if validationErr != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+		return
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Template not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update template"})
+		return
+	}
+
+	c.JSON(http.StatusOK, toTemplateResponse(tmpl, version))
+}
+
+// DeleteTemplate removes a template
+func (h *TemplateHandler) DeleteTemplate(c *gin.Context) {
+	userID := c.GetUint("userID")
+	templateID := c.Param("id")
+
+	result := database.DB.Where("id = ? AND user_id = ?", templateID, userID).Delete(&models.Template{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete template"})
+		return
+	}
+
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Template not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Template deleted successfully"})
+}
+
+// ListTemplateVersions returns every version of a template, newest first
+func (h *TemplateHandler) ListTemplateVersions(c *gin.Context) {
+	userID := c.GetUint("userID")
+	templateID := c.Param("id")
+
+	var tmpl models.Template
+	if err := database.DB.Where("id = ? AND user_id = ?", templateID, userID).First(&tmpl).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Template not found"})
+		return
+	}
+
+	var versions []models.TemplateVersion
+	if err := database.DB.Where("template_id = ?", tmpl.ID).Order("version DESC").Find(&versions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch template versions"})
+		return
+	}
+
+	response := make([]TemplateVersionResponse, len(versions))
+	for i, version := range versions {
+		response[i] = TemplateVersionResponse{
+			Version:   version.Version,
+			Subject:   version.Subject,
+			HTMLBody:  version.HTMLBody,
+			PlainBody: version.PlainBody,
+			CreatedAt: version.CreatedAt.Format("2006-01-02T15:04:05Z"),
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// PreviewTemplate renders a template version with sample variables. Missing
+// variables are reported as warnings rather than errors.
+func (h *TemplateHandler) PreviewTemplate(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	var req PreviewTemplateRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	_, version, err := loadTemplateVersion(userID, c.Param("id"), req.Version)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Template not found"})
+		return
+	}
+
+	preview, sendErr := previewTemplateVersion(version, req.Variables)
+	if sendErr != nil {
+		c.JSON(sendErr.Status, gin.H{"error": sendErr.Message})
+		return
+	}
+
+	c.JSON(http.StatusOK, preview)
+}
+
+// TestSendTemplate renders a template version and sends it to an address on
+// the account's test list. Test sends are not logged as emails.
+func (h *TemplateHandler) TestSendTemplate(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	var req TestSendTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !isTestRecipient(userID, req.To) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Recipient is not on the account's test list"})
+		return
+	}
+
+	from, sendErr := parseAddress("from", req.From)
+	if sendErr == nil {
+		sendErr = authorizeSender(userID, from.Address, h.cfg.AllowSenderSubdomains)
+	}
+	if sendErr != nil {
+		c.JSON(sendErr.Status, gin.H{"error": sendErr.Message})
+		return
+	}
+
+	_, version, err := loadTemplateVersion(userID, c.Param("id"), req.Version)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Template not found"})
+		return
+	}
+
+	preview, sendErr := previewTemplateVersion(version, req.Variables)
+	if sendErr != nil {
+		c.JSON(sendErr.Status, gin.H{"error": sendErr.Message})
+		return
+	}
+
+	// Send what a real send would render. Unlike the preview, rendering fails
+	// on missing variables instead of leaving them empty.
+	content, err := templates.Render(templates.Content{
+		Subject:   version.Subject,
+		HTMLBody:  version.HTMLBody,
+		PlainBody: version.PlainBody,
+	}, req.Variables)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":    "Failed to render template: " + err.Error(),
+			"warnings": preview.Warnings,
+		})
+		return
+	}
+
+	preview.Subject = content.Subject
+	preview.HTMLBody = content.HTMLBody
+	preview.PlainBody = content.PlainBody
+	if preview.PlainBody == "" && preview.HTMLBody != "" {
+		preview.PlainBody = htmlbody.ToText(preview.HTMLBody)
+	}
+
+	resp, err := h.postalClient.SendEmail(postal.SendEmailRequest{
+		To:        []string{req.To},
+		From:      from.String(),
+		Subject:   "[Test] " + preview.Subject,
+		HTMLBody:  preview.HTMLBody,
+		PlainBody: preview.PlainBody,
+	})
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": fmt.Sprintf("Failed to send test email: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"postal_message_id": resp.Data.MessageID,
+		"preview":           preview,
+	})
+}
+
+// previewTemplateVersion renders a version leniently for preview and test sends
+func previewTemplateVersion(version *models.TemplateVersion, variables map[string]interface{}) (*PreviewTemplateResponse, *sendError) {
+	content, warnings, err := templates.Preview(templates.Content{
+		Subject:   version.Subject,
+		HTMLBody:  version.HTMLBody,
+		PlainBody: version.PlainBody,
+	}, variables)
+	if err != nil {
+		return nil, &sendError{Status: http.StatusUnprocessableEntity, Message: "Failed to render template: " + err.Error()}
+	}
+
+	if warnings == nil {
+		warnings = []string{}
+	}
+
+	if warning := htmlbody.SizeWarning(content.HTMLBody); warning != "" {
+		warnings = append(warnings, warning)
+	}
+
+	// Match the send path, which generates a missing plain-text part
+	if content.PlainBody == "" && content.HTMLBody != "" {
+		content.PlainBody = htmlbody.ToText(content.HTMLBody)
+	}
+
+	return &PreviewTemplateResponse{
+		Version:   version.Version,
+		Subject:   content.Subject,
+		HTMLBody:  content.HTMLBody,
+		PlainBody: content.PlainBody,
+		Warnings:  warnings,
+	}, nil
+}
+
+// loadTemplateVersion loads a user's template and one of its versions.
+// A version of 0 selects the template's current version.
+func loadTemplateVersion(userID uint, templateID interface{}, versionNumber int) (*models.Template, *models.TemplateVersion, error) {
+	var tmpl models.Template
+	if err := database.DB.Where("id = ? AND user_id = ?", templateID, userID).First(&tmpl).Error; err != nil {
+		return nil, nil, err
+	}
+
+	if versionNumber == 0 {
+		versionNumber = tmpl.CurrentVersion
+	}
+
+	var version models.TemplateVersion
+	if err := database.DB.Where("template_id = ? AND version = ?", tmpl.ID, versionNumber).First(&version).Error; err != nil {
+		return nil, nil, err
+	}
+
+	return &tmpl, &version, nil
+}
+
+// validateTemplateContent checks that a template has a body and parses
+func validateTemplateContent(content templates.Content) error {
+	if content.Subject == "" {
+		return errors.New("subject is required")
+	}
+	if content.HTMLBody == "" && content.PlainBody == "" {
+		return errors.New("either html_body or plain_body is required")
+	}
+	return templates.Validate(content)
+}
+
+func toTemplateResponse(tmpl models.Template, version models.TemplateVersion) TemplateResponse {
+	return TemplateResponse{
+		ID:          tmpl.ID,
+		Name:        tmpl.Name,
+		Description: tmpl.Description,
+		Version:     version.Version,
+		Subject:     version.Subject,
+		HTMLBody:    version.HTMLBody,
+		PlainBody:   version.PlainBody,
+		CreatedAt:   tmpl.CreatedAt.Format("2006-01-02T15:04:05Z"),
+		UpdatedAt:   tmpl.UpdatedAt.Format("2006-01-02T15:04:05Z"),
+	}
+}
+
+func stringOr(value *string, fallback string) string {
+	if value != nil {
+		return *value
+	}
+	return fallback
+}
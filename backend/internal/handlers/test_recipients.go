@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"net/http"
+	"net/mail"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shohag/seentics-email/internal/database"
+	"github.com/shohag/seentics-email/internal/models"
+)
+
+type TestRecipientHandler struct{}
+
+func NewTestRecipientHandler() *TestRecipientHandler {
+	return &TestRecipientHandler{}
+}
+
+type AddTestRecipientRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// ListTestRecipients returns the addresses allowed to receive test sends
+func (h *TestRecipientHandler) ListTestRecipients(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	var recipients []models.TestRecipient
+	if err := database.DB.Where("user_id = ?", userID).Order("email").Find(&recipients).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch test recipients"})
+		return
+	}
+
+	c.JSON(http.StatusOK, recipients)
+}
+
+// AddTestRecipient allows an address to receive test sends
+func (h *TestRecipientHandler) AddTestRecipient(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	var req AddTestRecipientRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	email := strings.ToLower(req.Email)
+
+	var existing models.TestRecipient
+	if err := database.DB.Where("user_id = ? AND email = ?", userID, email).First(&existing).Error; err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Test recipient already exists"})
+		return
+	}
+
+	recipient := models.TestRecipient{
+		UserID: userID,
+		Email:  email,
+	}
+
+	if err := database.DB.Create(&recipient).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add test recipient"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, recipient)
+}
+
+// DeleteTestRecipient removes an address from the test list
+func (h *TestRecipientHandler) DeleteTestRecipient(c *gin.Context) {
+	userID := c.GetUint("userID")
+	recipientID := c.Param("id")
+
+	result := database.DB.Where("id = ? AND user_id = ?", recipientID, userID).Delete(&models.TestRecipient{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete test recipient"})
+		return
+	}
+
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Test recipient not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Test recipient deleted successfully"})
+}
+
+// isTestRecipient reports whether an address may receive test sends. The
+// account's own email address is always allowed.
+func isTestRecipient(userID uint, address string) bool {
+	parsed, err := mail.ParseAddress(address)
+	if err != nil {
+		return false
+	}
+	email := strings.ToLower(parsed.Address)
+
+	var user models.User
+	if err := database.DB.First(&user, userID).Error; err == nil && strings.EqualFold(user.Email, email) {
+		return true
+	}
+
+	var count int64
+	database.DB.Model(&models.TestRecipient{}).Where("user_id = ? AND email = ?", userID, email).Count(&count)
+	return count > 0
+}
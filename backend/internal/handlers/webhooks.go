@@ -1,22 +1,44 @@
 package handlers
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/hex"
+	"fmt"
 	"io"
+	"log"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"github.com/shohag/seentics-email/internal/bounces"
+	"github.com/shohag/seentics-email/internal/config"
 	"github.com/shohag/seentics-email/internal/database"
 	"github.com/shohag/seentics-email/internal/models"
 	"github.com/shohag/seentics-email/internal/postal"
+	"github.com/shohag/seentics-email/internal/suppressions"
+	"github.com/shohag/seentics-email/internal/webhooks"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-type WebhookHandler struct{}
+type WebhookHandler struct {
+	cfg          *config.Config
+	dispatcher   *webhooks.Dispatcher
+	redisClient  *redis.Client
+	postalClient *postal.Client
+}
 
-func NewWebhookHandler() *WebhookHandler {
-	return &WebhookHandler{}
+func NewWebhookHandler(cfg *config.Config, dispatcher *webhooks.Dispatcher, redisClient *redis.Client, postalClient *postal.Client) *WebhookHandler {
+	return &WebhookHandler{
+		cfg:          cfg,
+		dispatcher:   dispatcher,
+		redisClient:  redisClient,
+		postalClient: postalClient,
+	}
 }
 
 type CreateWebhookRequest struct {
@@ -24,42 +46,52 @@ type CreateWebhookRequest struct {
 	Events []string `json:"events" binding:"required"`
 }
 
+type UpdateWebhookRequest struct {
+	URL      *string  `json:"url" binding:"omitempty,url"`
+	Events   []string `json:"events"`
+	IsActive *bool    `json:"is_active"`
+}
+
+type RotateWebhookSecretRequest struct {
+	GracePeriodHours *int `json:"grace_period_hours" binding:"omitempty,min=0,max=168"`
+}
+
 type WebhookResponse struct {
-	ID              uint     `json:"id"`
-	URL             string   `json:"url"`
-	Events          []string `json:"events"`
-	Secret          string   `json:"secret,omitempty"` // Only on creation
-	IsActive        bool     `json:"is_active"`
-	LastTriggeredAt *string  `json:"last_triggered_at"`
-	CreatedAt       string   `json:"created_at"`
+	ID                      uint     `json:"id"`
+	URL                     string   `json:"url"`
+	Events                  []string `json:"events"`
+	Secret                  string   `json:"secret,omitempty"` // Only on creation and rotation
+	IsActive                bool     `json:"is_active"`
+	LastTriggeredAt         *string  `json:"last_triggered_at"`
+	PreviousSecretExpiresAt *string  `json:"previous_secret_expires_at,omitempty"`
+	ConsecutiveFailures     int      `json:"consecutive_failures"`
+	DisabledReason          string   `json:"disabled_reason,omitempty"`
+	DisabledAt              *string  `json:"disabled_at,omitempty"`
+	CreatedAt               string   `json:"created_at"`
 }
 
+const (
+	// defaultSecretGracePeriod is how long the old secret keeps signing after a rotation
+	defaultSecretGracePeriod = 24 * time.Hour
+
+	// unmatchedEventRetryWindow is how long after an event Postal is asked to
+	// retry it when no email log matches yet
+	unmatchedEventRetryWindow = 10 * time.Minute
+)
+
 // ListWebhooks returns all webhooks for the authenticated user
 func (h *WebhookHandler) ListWebhooks(c *gin.Context) {
 	userID := c.GetUint("userID")
 
-	var webhooks []models.Webhook
-	if err := database.DB.Where("user_id = ?", userID).Find(&webhooks).Error; err != nil {
+	var userWebhooks []models.Webhook
+	if err := database.DB.Where("user_id = ?", userID).Find(&userWebhooks).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch webhooks"})
 		return
 	}
 
-	response := make([]WebhookResponse, len(webhooks))
-	for i, wh := range webhooks {
-		var lastTriggered *string
-		if wh.LastTriggeredAt != nil {
-			formatted := wh.LastTriggeredAt.Format("2006-01-02T15:04:05Z")
-			lastTriggered = &formatted
-		}
-
-		response[i] = WebhookResponse{
-			ID:              wh.ID,
-			URL:             wh.URL,
-			Events:          []string{}, // Parse from JSON
-			IsActive:        wh.IsActive,
-			LastTriggeredAt: lastTriggered,
-			CreatedAt:       wh.CreatedAt.Format("2006-01-02T15:04:05Z"),
-		}
+	response := make([]WebhookResponse, len(userWebhooks))
+	for i, wh := range userWebhooks {
+		response[i] = toWebhookResponse(wh)
 	}
 
 	c.JSON(http.StatusOK, response)
@@ -75,6 +107,23 @@ func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
 		return
 	}
 
+	if err := webhooks.ValidateURL(c.Request.Context(), req.URL); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	events, err := webhooks.ValidateEventTypes(req.Events)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "available_events": webhooks.EventTypes()})
+		return
+	}
+
+	eventsJSON, err := webhooks.EncodeEvents(events)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encode events"})
+		return
+	}
+
 	// Generate webhook secret
 	secret, err := generateWebhookSecret()
 	if err != nil {
@@ -85,7 +134,7 @@ func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
 	webhook := models.Webhook{
 		UserID:   userID,
 		URL:      req.URL,
-		Events:   "[]", // Store as JSON
+		Events:   eventsJSON,
 		Secret:   secret,
 		IsActive: true,
 	}
@@ -98,13 +147,154 @@ func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
 	c.JSON(http.StatusCreated, WebhookResponse{
 		ID:        webhook.ID,
 		URL:       webhook.URL,
-		Events:    req.Events,
+		Events:    events,
 		Secret:    secret, // Return secret only on creation
 		IsActive:  webhook.IsActive,
 		CreatedAt: webhook.CreatedAt.Format("2006-01-02T15:04:05Z"),
 	})
 }
 
+// UpdateWebhook changes a webhook's URL, subscribed events or active state
+func (h *WebhookHandler) UpdateWebhook(c *gin.Context) {
+	userID := c.GetUint("userID")
+	webhookID := c.Param("id")
+
+	var req UpdateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var webhook models.Webhook
+	if err := database.DB.Where("id = ? AND user_id = ?", webhookID, userID).First(&webhook).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if req.URL != nil {
+		if err := webhooks.ValidateURL(c.Request.Context(), *req.URL); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		updates["url"] = *req.URL
+	}
+	if req.Events != nil {
+		events, err := webhooks.ValidateEventTypes(req.Events)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "available_events": webhooks.EventTypes()})
+			return
+		}
+
+		eventsJSON, err := webhooks.EncodeEvents(events)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encode events"})
+			return
+		}
+		updates["events"] = eventsJSON
+	}
+	if req.IsActive != nil {
+		updates["is_active"] = *req.IsActive
+		if *req.IsActive {
+			for column, value := range reenableUpdates(false) {
+				updates[column] = value
+			}
+		}
+	}
+
+	if len(updates) > 0 {
+		if err := database.DB.Model(&webhook).Updates(updates).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update webhook"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, toWebhookResponse(webhook))
+}
+
+// RotateWebhookSecret issues a new signing secret. The previous secret keeps
+// signing deliveries alongside the new one until the grace period ends.
+func (h *WebhookHandler) RotateWebhookSecret(c *gin.Context) {
+	userID := c.GetUint("userID")
+	webhookID := c.Param("id")
+
+	var req RotateWebhookSecretRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	var webhook models.Webhook
+	if err := database.DB.Where("id = ? AND user_id = ?", webhookID, userID).First(&webhook).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+		return
+	}
+
+	secret, err := generateWebhookSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate webhook secret"})
+		return
+	}
+
+	gracePeriod := defaultSecretGracePeriod
+	if req.GracePeriodHours != nil {
+		gracePeriod = time.Duration(*req.GracePeriodHours) * time.Hour
+	}
+
+	updates := map[string]interface{}{
+		"secret":                     secret,
+		"previous_secret":            "",
+		"previous_secret_expires_at": nil,
+	}
+	if gracePeriod > 0 {
+		expiresAt := time.Now().Add(gracePeriod)
+		updates["previous_secret"] = webhook.Secret
+		updates["previous_secret_expires_at"] = expiresAt
+	}
+
+	if err := database.DB.Model(&webhook).Updates(updates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to rotate webhook secret"})
+		return
+	}
+
+	response := toWebhookResponse(webhook)
+	response.Secret = secret // Return the new secret only on rotation
+	c.JSON(http.StatusOK, response)
+}
+
+// TestWebhook sends a synthetic signed event and reports the endpoint's response
+func (h *WebhookHandler) TestWebhook(c *gin.Context) {
+	userID := c.GetUint("userID")
+	webhookID := c.Param("id")
+
+	var webhook models.Webhook
+	if err := database.DB.Where("id = ? AND user_id = ?", webhookID, userID).First(&webhook).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+		return
+	}
+
+	delivery, err := h.dispatcher.Test(webhook)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send test event"})
+		return
+	}
+
+	// A successful ping re-enables an endpoint the circuit breaker switched off
+	if delivery.Success && !webhook.IsActive && webhook.DisabledAt != nil {
+		if err := database.DB.Model(&webhook).Updates(reenableUpdates(true)).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to re-enable webhook"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"delivery": delivery,
+		"webhook":  toWebhookResponse(webhook),
+	})
+}
+
 // DeleteWebhook removes a webhook
 func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
 	userID := c.GetUint("userID")
@@ -124,8 +314,87 @@ func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Webhook deleted successfully"})
 }
 
+// ListDeliveries returns the delivery attempts recorded for a webhook
+func (h *WebhookHandler) ListDeliveries(c *gin.Context) {
+	userID := c.GetUint("userID")
+	webhookID := c.Param("id")
+
+	var webhook models.Webhook
+	if err := database.DB.Where("id = ? AND user_id = ?", webhookID, userID).First(&webhook).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+		return
+	}
+
+	// Pagination parameters
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if limit > 100 {
+		limit = 100
+	}
+	offset := (page - 1) * limit
+
+	query := database.DB.Where("webhook_id = ?", webhook.ID)
+	switch c.Query("status") {
+	case "succeeded":
+		query = query.Where("success = ?", true)
+	case "failed":
+		query = query.Where("success = ?", false)
+	}
+	if eventID := c.Query("event_id"); eventID != "" {
+		query = query.Where("event_id = ?", eventID)
+	}
+
+	// Get total count
+	var total int64
+	query.Model(&models.WebhookDelivery{}).Count(&total)
+
+	var deliveries []models.WebhookDelivery
+	if err := query.Order("created_at DESC").Limit(limit).Offset(offset).Find(&deliveries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch deliveries"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"deliveries": deliveries,
+		"pagination": gin.H{
+			"page":  page,
+			"limit": limit,
+			"total": total,
+		},
+	})
+}
+
+// RedeliverDelivery replays a recorded delivery to the webhook endpoint
+func (h *WebhookHandler) RedeliverDelivery(c *gin.Context) {
+	userID := c.GetUint("userID")
+	webhookID := c.Param("id")
+	deliveryID := c.Param("delivery_id")
+
+	var webhook models.Webhook
+	if err := database.DB.Where("id = ? AND user_id = ?", webhookID, userID).First(&webhook).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+		return
+	}
+
+	var delivery models.WebhookDelivery
+	if err := database.DB.Where("id = ? AND webhook_id = ?", deliveryID, webhook.ID).First(&delivery).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Delivery not found"})
+		return
+	}
+
+	redelivery, err := h.dispatcher.Redeliver(webhook, delivery)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to redeliver event"})
+		return
+	}
+
+	c.JSON(http.StatusOK, redelivery)
+}
+
 // HandlePostalWebhook receives webhooks from Postal
 func (h *WebhookHandler) HandlePostalWebhook(c *gin.Context) {
+	ctx := c.Request.Context()
+
 	body, err := io.ReadAll(c.Request.Body)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
@@ -139,51 +408,334 @@ func (h *WebhookHandler) HandlePostalWebhook(c *gin.Context) {
 		return
 	}
 
-	// Extract message ID from payload
-	messageID := postal.GetMessageIDFromPayload(event.Payload)
-	if messageID == "" {
+	// Postal retries webhooks, so quietly acknowledge events already processed
+	if event.UUID != "" {
+		firstSeen, err := h.markEventProcessed(ctx, event.UUID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record webhook event"})
+			return
+		}
+		if !firstSeen {
+			c.JSON(http.StatusOK, gin.H{"message": "Duplicate event ignored"})
+			return
+		}
+	}
+
+	// Extract message reference from payload
+	ref := postal.GetMessageRefFromPayload(event.Payload)
+	if ref.ID == "" && ref.MessageID == "" {
 		c.JSON(http.StatusOK, gin.H{"message": "No message ID in payload"})
 		return
 	}
 
-	// Update email log based on event type
-	var emailLog models.EmailLog
-	if err := database.DB.Where("postal_message_id = ?", messageID).First(&emailLog).Error; err != nil {
-		c.JSON(http.StatusOK, gin.H{"message": "Email log not found"})
+	// Update the email log of exactly the recipient the event is about
+	emailLog, err := findEmailLog(ref)
+	if err != nil {
+		// A recent event may have arrived before the Postal message ID was
+		// stored on the email log, so let Postal retry it. Older events are
+		// for messages not sent through us, such as Postal's test sends.
+		if !retryUnmatchedEvent(event.OccurredAt(), time.Now()) {
+			c.JSON(http.StatusOK, gin.H{"message": "Email log not found"})
+			return
+		}
+		if event.UUID != "" {
+			h.unmarkEventProcessed(ctx, event.UUID)
+		}
+		c.JSON(http.StatusNotFound, gin.H{"error": "Email log not found"})
 		return
 	}
 
-	now := time.Now()
-	updates := make(map[string]interface{})
+	occurredAt := event.OccurredAt()
+	if occurredAt.IsZero() {
+		occurredAt = time.Now()
+	}
+
+	var status models.EmailStatus
+	var timestampColumn string
 
 	switch event.Event {
 	case postal.EventMessageDelivered:
-		updates["status"] = models.EmailStatusDelivered
-		updates["delivered_at"] = now
+		status = models.EmailStatusDelivered
+		timestampColumn = "delivered_at"
 	case postal.EventMessageBounced:
-		updates["status"] = models.EmailStatusBounced
-		updates["bounced_at"] = now
+		status = models.EmailStatusBounced
+		timestampColumn = "bounced_at"
 	case postal.EventMessageFailed:
-		updates["status"] = models.EmailStatusFailed
+		status = models.EmailStatusFailed
 	case postal.EventMessageOpened:
-		updates["opened_at"] = now
+		timestampColumn = "opened_at"
 	case postal.EventMessageClicked:
-		updates["clicked_at"] = now
+		timestampColumn = "clicked_at"
 	}
 
-	if len(updates) > 0 {
-		database.DB.Model(&emailLog).Updates(updates)
+	var bounce *bounceResult
+	if event.Event == postal.EventMessageBounced {
+		bounce = h.classifyBounce(event.Payload)
+	}
+
+	err = applyEmailEvent(emailLog.ID, status, timestampColumn, occurredAt)
+	if err == nil && bounce != nil {
+		err = recordBounce(emailLog.ID, bounce)
+	}
+	if err != nil {
+		// Let Postal retry the event
+		if event.UUID != "" {
+			h.unmarkEventProcessed(ctx, event.UUID)
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update email log"})
+		return
+	}
+
+	// Stop sending to addresses that bounce for good
+	if bounce != nil {
+		h.suppressBounced(emailLog, bounce)
 	}
 
 	// Forward to user webhooks
-	var webhooks []models.Webhook
-	database.DB.Where("user_id = ? AND is_active = ?", emailLog.UserID, true).Find(&webhooks)
+	if eventType := webhooks.EventTypeFromPostal(event.Event); eventType != "" {
+		data := gin.H{
+			"email_id":          emailLog.ID,
+			"message_id":        emailLog.MessageID,
+			"postal_message_id": emailLog.PostalMessageID,
+			"from":              emailLog.From,
+			"to":                emailLog.To,
+			"subject":           emailLog.Subject,
+			"occurred_at":       occurredAt.UTC(),
+		}
+		if status != "" {
+			data["status"] = status
+		} else {
+			data["status"] = emailLog.Status
+		}
+		if url, ok := event.Payload["url"].(string); ok {
+			data["url"] = url
+		}
+		if bounce != nil {
+			data["bounce_type"] = bounce.Type
+			data["diagnostic"] = bounce.Info.Diagnostic
+		}
 
-	// TODO: Implement webhook forwarding to user endpoints
+		h.dispatcher.Dispatch(emailLog.UserID, eventType, data)
+	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Webhook processed"})
 }
 
+// retryUnmatchedEvent reports whether an event without an email log is recent
+// enough to be retried. Events without a timestamp are not retried.
+func retryUnmatchedEvent(occurredAt, now time.Time) bool {
+	return !occurredAt.IsZero() && now.Sub(occurredAt) < unmatchedEventRetryWindow
+}
+
+// findEmailLog matches a Postal message reference to a recipient's email log
+func findEmailLog(ref postal.MessageRef) (*models.EmailLog, error) {
+	var emailLog models.EmailLog
+
+	if ref.ID != "" {
+		query := database.DB.Where("postal_message_id = ?", ref.ID)
+		if ref.Token != "" {
+			query = query.Where("postal_token = ?", ref.Token)
+		}
+		if err := query.First(&emailLog).Error; err == nil {
+			return &emailLog, nil
+		}
+	}
+
+	// Rows logged before per-recipient IDs were stored hold the shared
+	// Message-ID header, so the recipient address is needed to tell them apart
+	if ref.MessageID != "" && ref.To != "" {
+		err := database.DB.Where(`postal_message_id = ? AND LOWER("to") = LOWER(?)`, ref.MessageID, ref.To).
+			First(&emailLog).Error
+		if err == nil {
+			return &emailLog, nil
+		}
+	}
+
+	return nil, gorm.ErrRecordNotFound
+}
+
+// applyEmailEvent records a Postal event on an email log. Timestamps keep the
+// first occurrence and the status only ever moves forward, so replayed or
+// out-of-order events cannot undo later progress.
+func applyEmailEvent(emailLogID uint, status models.EmailStatus, timestampColumn string, occurredAt time.Time) error {
+	if timestampColumn != "" {
+		err := database.DB.Model(&models.EmailLog{}).Where("id = ?", emailLogID).
+			Update(timestampColumn, gorm.Expr("COALESCE("+timestampColumn+", ?)", occurredAt)).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	if status != "" {
+		err := database.DB.Model(&models.EmailLog{}).
+			Where("id = ? AND status IN ?", emailLogID, models.StatusesBefore(status)).
+			Update("status", status).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// bounceResult is a classified bounce
+type bounceResult struct {
+	Type models.BounceType
+	Info postal.BounceInfo
+}
+
+// ErrorMessage describes the bounce for the email log
+func (b *bounceResult) ErrorMessage() string {
+	label := strings.ToUpper(string(b.Type[:1])) + strings.ReplaceAll(string(b.Type[1:]), "_", " ") + " bounce"
+	if b.Info.Diagnostic == "" {
+		return label
+	}
+	return label + ": " + b.Info.Diagnostic
+}
+
+// classifyBounce parses a bounce event for its SMTP codes and diagnostic
+// text. Postal's bounce events often carry no diagnostic, in which case the
+// DSN is read from the body of Postal's bounce message.
+func (h *WebhookHandler) classifyBounce(payload map[string]interface{}) *bounceResult {
+	bounceID, info := postal.GetBounceFromPayload(payload)
+	if info.Diagnostic == "" && bounceID != "" && h.postalClient != nil {
+		body, err := h.postalClient.GetMessagePlainBody(bounceID)
+		if err != nil {
+			log.Printf("Failed to fetch bounce message %s: %v", bounceID, err)
+		} else {
+			info = postal.ParseDSN(body)
+		}
+	}
+
+	return &bounceResult{Type: bounces.Classify(info), Info: info}
+}
+
+// recordBounce stores a bounce's classification on an email log
+func recordBounce(emailLogID uint, bounce *bounceResult) error {
+	return database.DB.Model(&models.EmailLog{}).Where("id = ?", emailLogID).
+		Updates(map[string]interface{}{
+			"bounce_type":       bounce.Type,
+			"bounce_diagnostic": bounce.Info.Diagnostic,
+			"error_message":     bounce.ErrorMessage(),
+		}).Error
+}
+
+// suppressBounced suppresses a bounced recipient after a hard bounce, or
+// after repeated soft bounces within the configured window. Blocks are about
+// the sender rather than the address, so they never suppress.
+func (h *WebhookHandler) suppressBounced(emailLog *models.EmailLog, bounce *bounceResult) {
+	switch bounce.Type {
+	case models.BounceTypeHard:
+		suppressRecipient(emailLog, models.SuppressionReasonBounce, bounce.ErrorMessage())
+
+	case models.BounceTypeSoft, models.BounceTypeMailboxFull:
+		var count int64
+		err := database.DB.Model(&models.EmailLog{}).
+			Where(`user_id = ? AND LOWER("to") = LOWER(?) AND bounce_type IN ? AND bounced_at >= ?`,
+				emailLog.UserID, emailLog.To,
+				[]models.BounceType{models.BounceTypeSoft, models.BounceTypeMailboxFull},
+				time.Now().Add(-h.cfg.SoftBounceWindow)).
+			Count(&count).Error
+		if err != nil {
+			log.Printf("Failed to count soft bounces for %s: %v", emailLog.To, err)
+			return
+		}
+
+		if count >= int64(h.cfg.SoftBounceSuppressThreshold) {
+			details := fmt.Sprintf("%d soft bounces within %s; last: %s", count, h.cfg.SoftBounceWindow, bounce.ErrorMessage())
+			suppressRecipient(emailLog, models.SuppressionReasonBounce, details)
+		}
+	}
+}
+
+// suppressRecipient suppresses the recipient of an email log for its owner
+func suppressRecipient(emailLog *models.EmailLog, reason models.SuppressionReason, details string) {
+	if _, err := suppressions.Add(emailLog.UserID, emailLog.To, reason, details, &emailLog.ID); err != nil {
+		log.Printf("Failed to suppress %s for user %d: %v", emailLog.To, emailLog.UserID, err)
+	}
+}
+
+// markEventProcessed records a Postal event UUID and reports whether it was
+// seen for the first time. Redis keys expire after ProcessedWebhookEventTTL;
+// without Redis the UUID is stored in Postgres, where the scheduler prunes it.
+func (h *WebhookHandler) markEventProcessed(ctx context.Context, eventUUID string) (bool, error) {
+	if h.redisClient != nil {
+		firstSeen, err := h.redisClient.SetNX(ctx, processedEventKey(eventUUID), 1, models.ProcessedWebhookEventTTL).Result()
+		if err == nil {
+			return firstSeen, nil
+		}
+		log.Printf("Failed to record Postal event %s in Redis, falling back to database: %v", eventUUID, err)
+	}
+
+	result := database.DB.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.ProcessedWebhookEvent{UUID: eventUUID})
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected == 1, nil
+}
+
+// unmarkEventProcessed forgets an event UUID so a retry is processed again
+func (h *WebhookHandler) unmarkEventProcessed(ctx context.Context, eventUUID string) {
+	if h.redisClient != nil {
+		h.redisClient.Del(ctx, processedEventKey(eventUUID))
+	}
+	database.DB.Where("uuid = ?", eventUUID).Delete(&models.ProcessedWebhookEvent{})
+}
+
+func processedEventKey(eventUUID string) string {
+	return "postal:event:" + eventUUID
+}
+
+// reenableUpdates clears the circuit breaker state of a webhook
+func reenableUpdates(activate bool) map[string]interface{} {
+	updates := map[string]interface{}{
+		"consecutive_failures": 0,
+		"failing_since":        nil,
+		"disabled_reason":      "",
+		"disabled_at":          nil,
+	}
+	if activate {
+		updates["is_active"] = true
+	}
+	return updates
+}
+
+// toWebhookResponse converts a webhook model to its API representation
+func toWebhookResponse(wh models.Webhook) WebhookResponse {
+	var lastTriggered *string
+	if wh.LastTriggeredAt != nil {
+		formatted := wh.LastTriggeredAt.Format("2006-01-02T15:04:05Z")
+		lastTriggered = &formatted
+	}
+
+	var previousExpires *string
+	if wh.PreviousSecret != "" && wh.PreviousSecretExpiresAt != nil {
+		formatted := wh.PreviousSecretExpiresAt.Format("2006-01-02T15:04:05Z")
+		previousExpires = &formatted
+	}
+
+	var disabledAt *string
+	if wh.DisabledAt != nil {
+		formatted := wh.DisabledAt.Format("2006-01-02T15:04:05Z")
+		disabledAt = &formatted
+	}
+
+	return WebhookResponse{
+		ID:                      wh.ID,
+		URL:                     wh.URL,
+		Events:                  webhooks.DecodeEvents(wh.Events),
+		IsActive:                wh.IsActive,
+		LastTriggeredAt:         lastTriggered,
+		PreviousSecretExpiresAt: previousExpires,
+		ConsecutiveFailures:     wh.ConsecutiveFailures,
+		DisabledReason:          wh.DisabledReason,
+		DisabledAt:              disabledAt,
+		CreatedAt:               wh.CreatedAt.Format("2006-01-02T15:04:05Z"),
+	}
+}
+
 func generateWebhookSecret() (string, error) {
 	bytes := make([]byte, 32)
 	if _, err := rand.Read(bytes); err != nil {
@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRetryUnmatchedEvent(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		occurredAt time.Time
+		want       bool
+	}{
+		{"just happened", now.Add(-5 * time.Second), true},
+		{"inside window", now.Add(-unmatchedEventRetryWindow + time.Second), true},
+		{"window elapsed", now.Add(-unmatchedEventRetryWindow), false},
+		{"old event", now.Add(-24 * time.Hour), false},
+		{"no timestamp", time.Time{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := retryUnmatchedEvent(tt.occurredAt, now); got != tt.want {
+				t.Errorf("retryUnmatchedEvent() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,436 @@
+package htmlbody
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// cssRule is a style sheet rule with a selector that can be inlined
+type cssRule struct {
+	selector     selector
+	declarations []declaration
+	order        int
+}
+
+type declaration struct {
+	property  string
+	value     string
+	important bool
+}
+
+// selector is a chain of compound selectors joined by descendant (' ') or
+// child ('>') combinators, e.g. "table.main > td .note"
+type selector struct {
+	compounds   []compound
+	combinators []byte // combinators[i] joins compounds[i] and compounds[i+1]
+	specificity int
+}
+
+// compound is a single element selector such as td, .note or p#intro.lead
+type compound struct {
+	tag     string
+	id      string
+	classes []string
+}
+
+var compoundPattern = regexp.MustCompile(`^([a-zA-Z][a-zA-Z0-9-]*|\*)?((?:[.#][a-zA-Z0-9_-]+)*)$`)
+
+// inlineCSS moves the rules of <style> elements into style attributes.
+// Rules that cannot be inlined, such as @media blocks and pseudo-classes,
+// stay in a <style> element. It returns the number of rules left behind.
+func inlineCSS(doc *html.Node) int {
+	var styles []*html.Node
+	walkElements(doc, func(n *html.Node) {
+		if n.DataAtom == atom.Style {
+			styles = append(styles, n)
+		}
+	})
+
+	var rules []cssRule
+	kept := 0
+	for _, style := range styles {
+		inlinable, leftover, leftoverCount := parseStyleSheet(textContent(style), len(rules))
+		rules = append(rules, inlinable...)
+		kept += leftoverCount
+
+		if strings.TrimSpace(leftover) == "" {
+			style.Parent.RemoveChild(style)
+			continue
+		}
+		for style.FirstChild != nil {
+			style.RemoveChild(style.FirstChild)
+		}
+		style.AppendChild(&html.Node{Type: html.TextNode, Data: leftover})
+	}
+
+	if len(rules) == 0 {
+		return kept
+	}
+
+	// Later and more specific rules win, as in the browser
+	sort.SliceStable(rules, func(i, j int) bool {
+		if rules[i].selector.specificity != rules[j].selector.specificity {
+			return rules[i].selector.specificity < rules[j].selector.specificity
+		}
+		return rules[i].order < rules[j].order
+	})
+
+	walkElements(doc, func(n *html.Node) {
+		var applied []declaration
+		for _, rule := range rules {
+			if rule.selector.matches(n) {
+				applied = append(applied, rule.declarations...)
+			}
+		}
+		if len(applied) == 0 {
+			return
+		}
+
+		// Existing inline styles override the style sheet, except for
+		// !important style sheet declarations. Inline !important
+		// declarations override everything.
+		normal, important := splitImportant(applied)
+		inlineNormal, inlineImportant := splitImportant(parseDeclarations(getAttr(n, "style")))
+		merged := mergeDeclarations(normal, inlineNormal, important, inlineImportant)
+		setAttr(n, "style", formatDeclarations(merged))
+	})
+
+	return kept
+}
+
+// parseStyleSheet splits a style sheet into inlinable rules and the CSS that
+// has to stay in a <style> element
+func parseStyleSheet(css string, order int) ([]cssRule, string, int) {
+	css = stripComments(css)
+
+	var rules []cssRule
+	var leftover strings.Builder
+	leftoverCount := 0
+
+	for {
+		css = strings.TrimSpace(css)
+		if css == "" {
+			break
+		}
+
+		if strings.HasPrefix(css, "@") {
+			// At-rules either end with ';' (e.g. @import) or have a block
+			end := atRuleEnd(css)
+			leftover.WriteString(css[:end])
+			leftover.WriteString("\n")
+			leftoverCount++
+			css = css[end:]
+			continue
+		}
+
+		open := strings.Index(css, "{")
+		if open < 0 {
+			break
+		}
+		close := strings.Index(css[open:], "}")
+		if close < 0 {
+			break
+		}
+		close += open
+
+		selectors := css[:open]
+		body := css[open+1 : close]
+		css = css[close+1:]
+
+		declarations := parseDeclarations(body)
+		if len(declarations) == 0 {
+			continue
+		}
+
+		// An empty selector makes the whole rule invalid, as in the browser
+		list := strings.Split(selectors, ",")
+		if containsEmpty(list) {
+			continue
+		}
+
+		var unsupported []string
+		for _, raw := range list {
+			raw = strings.TrimSpace(raw)
+			sel, ok := parseSelector(raw)
+			if !ok {
+				unsupported = append(unsupported, raw)
+				continue
+			}
+			rules = append(rules, cssRule{selector: sel, declarations: declarations, order: order})
+			order++
+		}
+
+		if len(unsupported) > 0 {
+			leftover.WriteString(strings.Join(unsupported, ", ") + " {" + body + "}\n")
+			leftoverCount++
+		}
+	}
+
+	return rules, leftover.String(), leftoverCount
+}
+
+func containsEmpty(selectors []string) bool {
+	for _, raw := range selectors {
+		if strings.TrimSpace(raw) == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// atRuleEnd returns the end of the at-rule at the start of css
+func atRuleEnd(css string) int {
+	depth := 0
+	for i, r := range css {
+		switch r {
+		case ';':
+			if depth == 0 {
+				return i + 1
+			}
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return len(css)
+}
+
+func parseSelector(raw string) (selector, bool) {
+	var sel selector
+	if raw == "" {
+		return sel, false
+	}
+
+	// Normalise combinators so that tokens are separated by single spaces
+	raw = strings.ReplaceAll(raw, ">", " > ")
+	tokens := strings.Fields(raw)
+
+	combinator := byte(0)
+	for _, token := range tokens {
+		if token == ">" {
+			if combinator != 0 || len(sel.compounds) == 0 {
+				return sel, false
+			}
+			combinator = '>'
+			continue
+		}
+
+		c, specificity, ok := parseCompound(token)
+		if !ok {
+			return sel, false
+		}
+		if len(sel.compounds) > 0 {
+			if combinator == 0 {
+				combinator = ' '
+			}
+			sel.combinators = append(sel.combinators, combinator)
+		}
+		combinator = 0
+		sel.compounds = append(sel.compounds, c)
+		sel.specificity += specificity
+	}
+
+	return sel, combinator == 0 && len(sel.compounds) > 0
+}
+
+// parseCompound parses a compound selector and returns its specificity,
+// weighted as ids*10000 + classes*100 + tags
+func parseCompound(token string) (compound, int, bool) {
+	var c compound
+	match := compoundPattern.FindStringSubmatch(token)
+	if match == nil || token == "" {
+		return c, 0, false
+	}
+
+	specificity := 0
+	if match[1] != "" && match[1] != "*" {
+		c.tag = strings.ToLower(match[1])
+		specificity++
+	}
+
+	rest := match[2]
+	for rest != "" {
+		kind := rest[0]
+		rest = rest[1:]
+		end := strings.IndexAny(rest, ".#")
+		if end < 0 {
+			end = len(rest)
+		}
+		name := rest[:end]
+		rest = rest[end:]
+
+		if kind == '#' {
+			if c.id != "" && c.id != name {
+				return c, 0, false
+			}
+			c.id = name
+			specificity += 10000
+		} else {
+			c.classes = append(c.classes, name)
+			specificity += 100
+		}
+	}
+
+	return c, specificity, true
+}
+
+func (s selector) matches(n *html.Node) bool {
+	return matchFrom(n, s, len(s.compounds)-1)
+}
+
+// matchFrom checks compounds[0..i] against n and its ancestors
+func matchFrom(n *html.Node, s selector, i int) bool {
+	if !s.compounds[i].matches(n) {
+		return false
+	}
+	if i == 0 {
+		return true
+	}
+
+	if s.combinators[i-1] == '>' {
+		parent := n.Parent
+		return parent != nil && parent.Type == html.ElementNode && matchFrom(parent, s, i-1)
+	}
+
+	for ancestor := n.Parent; ancestor != nil && ancestor.Type == html.ElementNode; ancestor = ancestor.Parent {
+		if matchFrom(ancestor, s, i-1) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c compound) matches(n *html.Node) bool {
+	if n.Type != html.ElementNode {
+		return false
+	}
+	if c.tag != "" && c.tag != n.Data {
+		return false
+	}
+	if c.id != "" && getAttr(n, "id") != c.id {
+		return false
+	}
+	if len(c.classes) > 0 {
+		classes := strings.Fields(getAttr(n, "class"))
+		for _, want := range c.classes {
+			found := false
+			for _, class := range classes {
+				if class == want {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// parseDeclarations parses "color: red; background: url(a;b)" into
+// declarations, ignoring semicolons inside parentheses and quotes
+func parseDeclarations(body string) []declaration {
+	var declarations []declaration
+	for _, part := range splitDeclarations(body) {
+		colon := strings.Index(part, ":")
+		if colon < 0 {
+			continue
+		}
+		property := strings.ToLower(strings.TrimSpace(part[:colon]))
+		value := strings.TrimSpace(part[colon+1:])
+		if property == "" || value == "" {
+			continue
+		}
+
+		important := false
+		if lower := strings.ToLower(value); strings.HasSuffix(lower, "!important") {
+			important = true
+			value = strings.TrimSpace(value[:len(value)-len("!important")])
+		}
+		declarations = append(declarations, declaration{property: property, value: value, important: important})
+	}
+	return declarations
+}
+
+func splitDeclarations(body string) []string {
+	var parts []string
+	depth := 0
+	var quote rune
+	start := 0
+	for i, r := range body {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '(':
+			depth++
+		case r == ')':
+			if depth > 0 {
+				depth--
+			}
+		case r == ';' && depth == 0:
+			parts = append(parts, body[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, body[start:])
+}
+
+// splitImportant separates normal declarations from !important ones
+func splitImportant(declarations []declaration) (normal, important []declaration) {
+	for _, decl := range declarations {
+		if decl.important {
+			important = append(important, decl)
+		} else {
+			normal = append(normal, decl)
+		}
+	}
+	return normal, important
+}
+
+// mergeDeclarations combines declaration lists, later lists taking precedence
+func mergeDeclarations(lists ...[]declaration) []declaration {
+	var merged []declaration
+	index := make(map[string]int)
+	for _, list := range lists {
+		for _, decl := range list {
+			if i, ok := index[decl.property]; ok {
+				merged[i] = decl
+				continue
+			}
+			index[decl.property] = len(merged)
+			merged = append(merged, decl)
+		}
+	}
+	return merged
+}
+
+func formatDeclarations(declarations []declaration) string {
+	parts := make([]string, 0, len(declarations))
+	for _, decl := range declarations {
+		value := decl.value
+		if decl.important {
+			value += " !important"
+		}
+		parts = append(parts, decl.property+": "+value)
+	}
+	return strings.Join(parts, "; ")
+}
+
+var commentPattern = regexp.MustCompile(`(?s)/\*.*?\*/`)
+
+func stripComments(css string) string {
+	return commentPattern.ReplaceAllString(css, "")
+}
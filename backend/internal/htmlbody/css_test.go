@@ -0,0 +1,83 @@
+package htmlbody
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestInlineCSS(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		want         string
+		wantWarnings []string
+	}{
+		{
+			name: "more specific selectors win",
+			body: `<style>p{color:red} .a{color:blue} #b{color:green}</style><p>x</p><p class="a">y</p><p class="a" id="b">z</p>`,
+			want: `<html><head></head><body><p style="color: red">x</p><p class="a" style="color: blue">y</p><p class="a" id="b" style="color: green">z</p></body></html>`,
+		},
+		{
+			name: "later rules win on equal specificity",
+			body: `<style>.a{color:red} .b{color:blue}</style><style>.a{color:green}</style><p class="a b">x</p>`,
+			want: `<html><head></head><body><p class="a b" style="color: green">x</p></body></html>`,
+		},
+		{
+			name: "inline styles override the style sheet",
+			body: `<style>p{color:red;margin:0}</style><p style="color:blue">x</p>`,
+			want: `<html><head></head><body><p style="color: blue; margin: 0">x</p></body></html>`,
+		},
+		{
+			name: "important style sheet declarations override inline styles",
+			body: `<style>p{color:red !important;margin:0}</style><p style="color:blue;margin:4px">x</p>`,
+			want: `<html><head></head><body><p style="margin: 4px; color: red !important">x</p></body></html>`,
+		},
+		{
+			name: "important inline declarations override everything",
+			body: `<style>p{color:red !important}</style><p style="color:pink !important">x</p>`,
+			want: `<html><head></head><body><p style="color: pink !important">x</p></body></html>`,
+		},
+		{
+			name: "descendant and child combinators",
+			body: `<style>div p{padding:1px} div>span{color:red}</style><div><p>x</p><span>y</span></div><p>z</p><div><p><span>w</span></p></div>`,
+			want: `<html><head></head><body><div><p style="padding: 1px">x</p><span style="color: red">y</span></div><p>z</p><div><p style="padding: 1px"><span>w</span></p></div></body></html>`,
+		},
+		{
+			name: "semicolons inside quotes and url()",
+			body: `<style>p{font-family:"a;b";background:url(data:image/png;base64,AAAA)}</style><p>x</p>`,
+			want: `<html><head></head><body><p style="font-family: &#34;a;b&#34;; background: url(data:image/png;base64,AAAA)">x</p></body></html>`,
+		},
+		{
+			name:         "unsupported selectors stay in a style block",
+			body:         `<style>a:hover{color:red} p, a:hover{margin:0} @media (max-width:600px){p{color:blue}}</style><p>x</p><a>y</a>`,
+			want:         "<html><head><style>a:hover {color:red}\na:hover {margin:0}\n@media (max-width:600px){p{color:blue}}\n</style></head><body><p style=\"margin: 0\">x</p><a>y</a></body></html>",
+			wantWarnings: []string{"3 CSS rules could not be inlined and were left in a <style> block"},
+		},
+		{
+			name:         "rules with an empty selector are dropped",
+			body:         `<style>a[href]{c:d}, p{color:red}</style><p>x</p>`,
+			want:         "<html><head><style>a[href] {c:d}\n</style></head><body><p>x</p></body></html>",
+			wantWarnings: []string{"1 CSS rules could not be inlined and were left in a <style> block"},
+		},
+		{
+			name: "comments are ignored",
+			body: `<style>/* p{color:red} */ p{color:blue}</style><p>x</p>`,
+			want: `<html><head></head><body><p style="color: blue">x</p></body></html>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, warnings, err := Prepare(tt.body, Options{InlineCSS: true})
+			if err != nil {
+				t.Fatalf("Prepare: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("body:\n got %s\nwant %s", got, tt.want)
+			}
+			if !reflect.DeepEqual(warnings, tt.wantWarnings) {
+				t.Errorf("warnings = %q, want %q", warnings, tt.wantWarnings)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+// Package htmlbody processes HTML email bodies before they are sent.
+package htmlbody
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// GmailClipBytes is the size above which Gmail clips a message body
+const GmailClipBytes = 102 * 1024
+
+// Options selects the steps of the pre-send pipeline
+type Options struct {
+	InlineCSS bool // Move <style> rules into style attributes
+	Sanitize  bool // Strip scripts, event handlers and javascript: URLs
+}
+
+// Prepare runs the selected pipeline steps over an HTML body and returns the
+// processed body along with warnings about what could not be handled
+func Prepare(body string, opts Options) (string, []string, error) {
+	if !opts.InlineCSS && !opts.Sanitize {
+		return body, nil, nil
+	}
+
+	doc, err := html.Parse(strings.NewReader(body))
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to parse html_body: %w", err)
+	}
+
+	var warnings []string
+
+	if opts.Sanitize {
+		if removed := sanitize(doc); removed > 0 {
+			warnings = append(warnings, fmt.Sprintf("Removed %d scripts, event handlers or unsafe URLs from html_body", removed))
+		}
+	}
+
+	if opts.InlineCSS {
+		if kept := inlineCSS(doc); kept > 0 {
+			warnings = append(warnings, fmt.Sprintf("%d CSS rules could not be inlined and were left in a <style> block", kept))
+		}
+	}
+
+	var out strings.Builder
+	if err := html.Render(&out, doc); err != nil {
+		return "", nil, fmt.Errorf("failed to render html_body: %w", err)
+	}
+
+	return out.String(), warnings, nil
+}
+
+// SizeWarning returns a warning if Gmail will clip the body, or ""
+func SizeWarning(body string) string {
+	if len(body) <= GmailClipBytes {
+		return ""
+	}
+	return fmt.Sprintf("html_body is %d bytes; Gmail clips messages larger than %d bytes", len(body), GmailClipBytes)
+}
+
+// walkElements calls fn for every element node in document order
+func walkElements(n *html.Node, fn func(*html.Node)) {
+	for child := n.FirstChild; child != nil; {
+		// fn may detach child, so find the next sibling first
+		next := child.NextSibling
+		if child.Type == html.ElementNode {
+			fn(child)
+		}
+		if child.Parent != nil {
+			walkElements(child, fn)
+		}
+		child = next
+	}
+}
+
+func getAttr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func setAttr(n *html.Node, key, value string) {
+	for i, a := range n.Attr {
+		if a.Key == key {
+			n.Attr[i].Val = value
+			return
+		}
+	}
+	n.Attr = append(n.Attr, html.Attribute{Key: key, Val: value})
+}
+
+// textContent returns the concatenated text of a node's children
+func textContent(n *html.Node) string {
+	var b strings.Builder
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == html.TextNode {
+			b.WriteString(child.Data)
+		}
+	}
+	return b.String()
+}
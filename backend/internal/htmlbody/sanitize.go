@@ -0,0 +1,77 @@
+package htmlbody
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// removedElements never belong in an email body and are dropped with their content
+var removedElements = map[atom.Atom]bool{
+	atom.Script:   true,
+	atom.Iframe:   true,
+	atom.Object:   true,
+	atom.Embed:    true,
+	atom.Frame:    true,
+	atom.Frameset: true,
+	atom.Applet:   true,
+}
+
+// urlAttributes hold URLs that must not use the javascript: scheme
+var urlAttributes = map[string]bool{
+	"href":       true,
+	"src":        true,
+	"action":     true,
+	"formaction": true,
+	"background": true,
+	"poster":     true,
+	"xlink:href": true,
+}
+
+// sanitize removes scripts, embedded content, event handler attributes and
+// javascript: URLs. It returns the number of elements and attributes removed.
+func sanitize(doc *html.Node) int {
+	removed := 0
+
+	var remove []*html.Node
+	walkElements(doc, func(n *html.Node) {
+		if removedElements[n.DataAtom] {
+			remove = append(remove, n)
+			return
+		}
+
+		attrs := n.Attr[:0]
+		for _, a := range n.Attr {
+			key := strings.ToLower(a.Key)
+			if strings.HasPrefix(key, "on") || (urlAttributes[key] && isScriptURL(a.Val)) {
+				removed++
+				continue
+			}
+			attrs = append(attrs, a)
+		}
+		n.Attr = attrs
+	})
+
+	for _, n := range remove {
+		if n.Parent != nil {
+			n.Parent.RemoveChild(n)
+			removed++
+		}
+	}
+
+	return removed
+}
+
+// isScriptURL detects javascript: and vbscript: URLs, including obfuscated
+// forms with embedded whitespace or control characters
+func isScriptURL(value string) bool {
+	var b strings.Builder
+	for _, r := range value {
+		if r > ' ' {
+			b.WriteRune(r)
+		}
+	}
+	normalized := strings.ToLower(b.String())
+	return strings.HasPrefix(normalized, "javascript:") || strings.HasPrefix(normalized, "vbscript:")
+}
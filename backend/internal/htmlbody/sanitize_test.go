@@ -0,0 +1,54 @@
+package htmlbody
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSanitize(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		want         string
+		wantWarnings []string
+	}{
+		{
+			name:         "obfuscated javascript URLs",
+			body:         `<a href=" jav&#x09;ascript:alert(1)">a</a><a href="JaVaScRiPt&colon;alert(1)">b</a><a href="&#106;avascript:alert(1)">c</a><a href="vbscript:msgbox">d</a>`,
+			want:         `<html><head></head><body><a>a</a><a>b</a><a>c</a><a>d</a></body></html>`,
+			wantWarnings: []string{"Removed 4 scripts, event handlers or unsafe URLs from html_body"},
+		},
+		{
+			name:         "event handlers in any case",
+			body:         `<img src="https://example.com/a.png" onerror="alert(1)" ONCLICK="x()">`,
+			want:         `<html><head></head><body><img src="https://example.com/a.png"/></body></html>`,
+			wantWarnings: []string{"Removed 2 scripts, event handlers or unsafe URLs from html_body"},
+		},
+		{
+			name:         "scripts and embedded content",
+			body:         `<p>x</p><script>alert(1)</script><iframe src="https://example.com"></iframe><svg><a xlink:href="javascript:alert(1)">f</a><script>alert(1)</script></svg><form action="javascript:x"></form>`,
+			want:         `<html><head></head><body><p>x</p><svg><a>f</a></svg><form></form></body></html>`,
+			wantWarnings: []string{"Removed 5 scripts, event handlers or unsafe URLs from html_body"},
+		},
+		{
+			name: "safe URLs are kept",
+			body: `<a href="https://example.com/javascript:ok">a</a><a href="mailto:a@example.com">b</a><a href="#top">c</a>`,
+			want: `<html><head></head><body><a href="https://example.com/javascript:ok">a</a><a href="mailto:a@example.com">b</a><a href="#top">c</a></body></html>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, warnings, err := Prepare(tt.body, Options{Sanitize: true})
+			if err != nil {
+				t.Fatalf("Prepare: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("body:\n got %s\nwant %s", got, tt.want)
+			}
+			if !reflect.DeepEqual(warnings, tt.wantWarnings) {
+				t.Errorf("warnings = %q, want %q", warnings, tt.wantWarnings)
+			}
+		})
+	}
+}
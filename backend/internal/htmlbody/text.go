@@ -0,0 +1,273 @@
+package htmlbody
+
+import (
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// ToText converts an HTML body to a plain-text alternative. Headings are
+// underlined, lists keep their bullets or numbers and links are followed by
+// their URL in parentheses.
+func ToText(body string) string {
+	doc, err := html.Parse(strings.NewReader(body))
+	if err != nil {
+		return ""
+	}
+
+	c := &textConverter{}
+	c.walk(doc)
+	return c.result()
+}
+
+// list tracks the numbering of an open ul or ol element
+type list struct {
+	ordered bool
+	index   int
+}
+
+type textConverter struct {
+	buf          strings.Builder
+	newlines     int  // Trailing newlines in buf
+	pendingSpace bool // Whitespace seen since the last word
+	pre          int  // Depth of open pre elements
+	lists        []*list
+}
+
+func (c *textConverter) walk(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		c.text(n.Data)
+		return
+	case html.ElementNode:
+		c.element(n)
+		return
+	case html.CommentNode, html.DoctypeNode:
+		return
+	}
+
+	c.children(n)
+}
+
+func (c *textConverter) children(n *html.Node) {
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		c.walk(child)
+	}
+}
+
+func (c *textConverter) element(n *html.Node) {
+	switch n.DataAtom {
+	case atom.Script, atom.Style, atom.Head, atom.Title, atom.Noscript, atom.Template:
+		return
+
+	case atom.Br:
+		c.newline(false)
+
+	case atom.Hr:
+		c.block(2)
+		c.write("--------------------")
+		c.block(2)
+
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		c.block(2)
+		start := c.buf.Len()
+		c.children(n)
+		heading := c.buf.String()[start:]
+		if length := len([]rune(strings.TrimSpace(heading))); length > 0 {
+			underline := "-"
+			if n.DataAtom == atom.H1 {
+				underline = "="
+			}
+			c.newline(true)
+			c.write(strings.Repeat(underline, length))
+		}
+		c.block(2)
+
+	case atom.Ul, atom.Ol:
+		if len(c.lists) == 0 {
+			c.block(2)
+		} else {
+			c.block(1)
+		}
+		c.lists = append(c.lists, &list{ordered: n.DataAtom == atom.Ol, index: listStart(n)})
+		c.children(n)
+		c.lists = c.lists[:len(c.lists)-1]
+		if len(c.lists) == 0 {
+			c.block(2)
+		} else {
+			c.block(1)
+		}
+
+	case atom.Li:
+		c.block(1)
+		marker := "* "
+		depth := len(c.lists)
+		if depth > 0 {
+			current := c.lists[depth-1]
+			if current.ordered {
+				marker = strconv.Itoa(current.index) + ". "
+				current.index++
+			}
+		} else {
+			depth = 1
+		}
+		c.write(strings.Repeat("  ", depth-1) + marker)
+		c.children(n)
+		c.block(1)
+
+	case atom.A:
+		start := c.buf.Len()
+		c.children(n)
+		href := strings.TrimSpace(getAttr(n, "href"))
+		if linkWorthShowing(href) {
+			label := strings.TrimSpace(c.buf.String()[start:])
+			if label != href && label != strings.TrimPrefix(href, "mailto:") {
+				if label == "" {
+					c.write(href)
+				} else {
+					c.write(" (" + href + ")")
+				}
+			}
+		}
+
+	case atom.Img:
+		if alt := strings.TrimSpace(getAttr(n, "alt")); alt != "" {
+			c.text(alt)
+		}
+
+	case atom.Pre:
+		c.block(2)
+		c.pre++
+		c.children(n)
+		c.pre--
+		c.block(2)
+
+	case atom.P, atom.Blockquote, atom.Table:
+		c.block(2)
+		c.children(n)
+		c.block(2)
+
+	case atom.Div, atom.Section, atom.Article, atom.Header, atom.Footer, atom.Nav, atom.Aside,
+		atom.Main, atom.Tr, atom.Dl, atom.Dt, atom.Dd, atom.Figure, atom.Figcaption, atom.Address:
+		c.block(1)
+		c.children(n)
+		c.block(1)
+
+	case atom.Td, atom.Th:
+		c.pendingSpace = true
+		c.children(n)
+		c.pendingSpace = true
+
+	default:
+		c.children(n)
+	}
+}
+
+// text writes a text node, collapsing whitespace outside pre elements
+func (c *textConverter) text(data string) {
+	if c.pre > 0 {
+		c.raw(data)
+		return
+	}
+
+	words := strings.Fields(data)
+	if len(words) == 0 {
+		if data != "" {
+			c.pendingSpace = true
+		}
+		return
+	}
+
+	if isSpace(data[0]) {
+		c.pendingSpace = true
+	}
+	for i, word := range words {
+		if i > 0 {
+			c.pendingSpace = true
+		}
+		c.write(word)
+	}
+	if isSpace(data[len(data)-1]) {
+		c.pendingSpace = true
+	}
+}
+
+// write appends inline text, preceded by a space if whitespace was pending
+func (c *textConverter) write(s string) {
+	if s == "" {
+		return
+	}
+	if c.pendingSpace && c.buf.Len() > 0 && c.newlines == 0 {
+		c.buf.WriteByte(' ')
+	}
+	c.pendingSpace = false
+	c.raw(s)
+}
+
+// raw appends text as is and tracks trailing newlines
+func (c *textConverter) raw(s string) {
+	if s == "" {
+		return
+	}
+	c.buf.WriteString(s)
+	trimmed := strings.TrimRight(s, "\n")
+	if trimmed == "" {
+		c.newlines += len(s)
+	} else {
+		c.newlines = len(s) - len(trimmed)
+	}
+}
+
+// newline ends the current line. With force, an empty line is ended too.
+func (c *textConverter) newline(force bool) {
+	c.pendingSpace = false
+	if c.newlines == 0 || force {
+		c.raw("\n")
+	}
+}
+
+// block makes sure the output ends with at least n newlines, so that the
+// next block starts on a fresh line (n=1) or after a blank line (n=2)
+func (c *textConverter) block(n int) {
+	c.pendingSpace = false
+	if c.buf.Len() == 0 {
+		return
+	}
+	for c.newlines < n {
+		c.raw("\n")
+	}
+}
+
+func (c *textConverter) result() string {
+	lines := strings.Split(c.buf.String(), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	text := strings.Join(lines, "\n")
+	for strings.Contains(text, "\n\n\n") {
+		text = strings.ReplaceAll(text, "\n\n\n", "\n\n")
+	}
+	return strings.TrimSpace(text)
+}
+
+// listStart returns the first number of an ordered list
+func listStart(n *html.Node) int {
+	if start, err := strconv.Atoi(getAttr(n, "start")); err == nil {
+		return start
+	}
+	return 1
+}
+
+// linkWorthShowing skips anchors and script links, which mean nothing in text
+func linkWorthShowing(href string) bool {
+	if href == "" || strings.HasPrefix(href, "#") {
+		return false
+	}
+	return !strings.HasPrefix(strings.ToLower(href), "javascript:")
+}
+
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r' || b == '\f'
+}
@@ -0,0 +1,83 @@
+package htmlbody
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+func TestToTextGolden(t *testing.T) {
+	inputs, err := filepath.Glob(filepath.Join("testdata", "*.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inputs) == 0 {
+		t.Fatal("no test inputs in testdata")
+	}
+
+	for _, input := range inputs {
+		golden := input[:len(input)-len(".html")] + ".txt"
+		t.Run(filepath.Base(input), func(t *testing.T) {
+			body, err := os.ReadFile(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := ToText(string(body))
+
+			if *update {
+				if err := os.WriteFile(golden, []byte(got), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("%v (run with -update to create it)", err)
+			}
+			if got != string(want) {
+				t.Errorf("ToText(%s) mismatch:\n--- got ---\n%s\n--- want ---\n%s", input, got, want)
+			}
+		})
+	}
+}
+
+func TestToText(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{
+			name: "link text equal to the URL",
+			body: `<a href="https://example.com">https://example.com</a>`,
+			want: "https://example.com",
+		},
+		{
+			name: "whitespace is collapsed",
+			body: "<p>one\n   two</p>\n\n<p>three</p>",
+			want: "one two\n\nthree",
+		},
+		{
+			name: "line breaks",
+			body: `<p>one<br>two</p>`,
+			want: "one\ntwo",
+		},
+		{
+			name: "invisible content is dropped",
+			body: `<head><title>T</title><style>p{}</style></head><body><p>x</p><script>y()</script></body>`,
+			want: "x",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ToText(tt.body); got != tt.want {
+				t.Errorf("ToText = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
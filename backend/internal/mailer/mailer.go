@@ -0,0 +1,286 @@
+package mailer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shohag/seentics-email/internal/config"
+	"github.com/shohag/seentics-email/internal/database"
+	"github.com/shohag/seentics-email/internal/domains"
+	"github.com/shohag/seentics-email/internal/models"
+	"github.com/shohag/seentics-email/internal/postal"
+	"github.com/shohag/seentics-email/internal/queue"
+	"github.com/shohag/seentics-email/internal/suppressions"
+	"gorm.io/gorm"
+)
+
+// Message is an outgoing email whose EmailLog rows already exist
+type Message struct {
+	MessageID string                  `json:"message_id"`
+	UserID    uint                    `json:"user_id"`
+	Request   postal.SendEmailRequest `json:"request"`
+}
+
+// Mailer hands messages to Postal, through the send queue when one is configured
+type Mailer struct {
+	postalClient          *postal.Client
+	queue                 *queue.Queue
+	allowSenderSubdomains bool
+}
+
+func New(cfg *config.Config, postalClient *postal.Client, sendQueue *queue.Queue) *Mailer {
+	return &Mailer{
+		postalClient:          postalClient,
+		queue:                 sendQueue,
+		allowSenderSubdomains: cfg.AllowSenderSubdomains,
+	}
+}
+
+// Submit queues a message for delivery and returns nil. Without a queue, or
+// if enqueueing fails, the message is delivered inline and Postal's response
+// is returned.
+func (m *Mailer) Submit(ctx context.Context, msg *Message) (*postal.SendEmailResponse, error) {
+	if m.queue != nil {
+		err := m.queue.Enqueue(ctx, msg)
+		if err == nil {
+			return nil, nil
+		}
+		log.Printf("Failed to queue message %s, sending inline: %v", msg.MessageID, err)
+	}
+
+	resp, err := m.Deliver(msg)
+	if err != nil {
+		m.MarkFailed(msg.MessageID, err)
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// Schedule stores a message to be submitted at sendAt. It runs on the given
+// transaction so the message and its email logs are created together.
+func (m *Mailer) Schedule(tx *gorm.DB, msg *Message, sendAt time.Time) error {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+
+	scheduled := models.ScheduledEmail{
+		UserID:    msg.UserID,
+		MessageID: msg.MessageID,
+		SendAt:    sendAt,
+		Payload:   string(payload),
+	}
+
+	if err := tx.Create(&scheduled).Error; err != nil {
+		return fmt.Errorf("failed to schedule message: %w", err)
+	}
+
+	return nil
+}
+
+// SubmitScheduled claims a due scheduled message and submits it. Claiming
+// deletes the row, so each message is submitted once even with several
+// instances running. It returns false if the message was already claimed
+// or cancelled.
+//
+// The checks made when the message was accepted are repeated, since the
+// sender domain or the suppression list may have changed in the meantime.
+func (m *Mailer) SubmitScheduled(ctx context.Context, scheduled models.ScheduledEmail) (bool, error) {
+	result := database.DB.Delete(&models.ScheduledEmail{}, scheduled.ID)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+
+	var msg Message
+	if err := json.Unmarshal([]byte(scheduled.Payload), &msg); err != nil {
+		m.MarkFailed(scheduled.MessageID, fmt.Errorf("invalid scheduled message: %w", err))
+		return true, err
+	}
+
+	if err := m.authorizeSender(&msg); err != nil {
+		m.MarkFailed(msg.MessageID, err)
+		return true, err
+	}
+
+	remaining, err := m.dropSuppressed(&msg)
+	if err != nil {
+		m.MarkFailed(msg.MessageID, err)
+		return true, err
+	}
+	if remaining == 0 {
+		return true, nil
+	}
+
+	database.DB.Model(&models.EmailLog{}).
+		Where("message_id = ? AND status = ?", msg.MessageID, models.EmailStatusScheduled).
+		Update("status", models.EmailStatusQueued)
+
+	_, err = m.Submit(ctx, &msg)
+	return true, err
+}
+
+// authorizeSender checks that the sender domain is still verified
+func (m *Mailer) authorizeSender(msg *Message) error {
+	from := msg.Request.From
+	if address, err := mail.ParseAddress(from); err == nil {
+		from = address.Address
+	}
+
+	authorized, err := domains.AuthorizesSender(msg.UserID, from, m.allowSenderSubdomains)
+	if err != nil {
+		return fmt.Errorf("failed to check sender domain: %w", err)
+	}
+	if !authorized {
+		return fmt.Errorf("sender domain %s is no longer a verified domain of this account", domains.SenderDomain(from))
+	}
+	return nil
+}
+
+// dropSuppressed removes recipients suppressed since the message was accepted
+// and marks their email logs failed. It returns how many recipients remain.
+func (m *Mailer) dropSuppressed(msg *Message) (int, error) {
+	req := &msg.Request
+
+	var all []string
+	for _, list := range [][]string{req.To, req.CC, req.BCC} {
+		for _, recipient := range list {
+			all = append(all, recipientAddress(recipient))
+		}
+	}
+
+	suppressedSet, err := suppressions.Suppressed(msg.UserID, all)
+	if err != nil {
+		return 0, fmt.Errorf("failed to check suppressions: %w", err)
+	}
+	if len(suppressedSet) == 0 {
+		return len(all), nil
+	}
+
+	filter := func(list []string) []string {
+		kept := list[:0:0]
+		for _, recipient := range list {
+			if !suppressedSet[suppressions.Normalize(recipientAddress(recipient))] {
+				kept = append(kept, recipient)
+			}
+		}
+		return kept
+	}
+	req.To, req.CC, req.BCC = filter(req.To), filter(req.CC), filter(req.BCC)
+
+	suppressed := make([]string, 0, len(suppressedSet))
+	for email := range suppressedSet {
+		suppressed = append(suppressed, email)
+	}
+
+	err = database.DB.Model(&models.EmailLog{}).
+		Where(`message_id = ? AND LOWER("to") IN ? AND status IN ?`,
+			msg.MessageID, suppressed, models.StatusesBefore(models.EmailStatusSent)).
+		Updates(map[string]interface{}{
+			"status":        models.EmailStatusFailed,
+			"error_message": "Recipient is suppressed",
+		}).Error
+	if err != nil {
+		log.Printf("Failed to mark suppressed recipients of message %s: %v", msg.MessageID, err)
+	}
+
+	return len(req.To) + len(req.CC) + len(req.BCC), nil
+}
+
+// recipientAddress returns the bare address of a formatted recipient
+func recipientAddress(recipient string) string {
+	if address, err := mail.ParseAddress(recipient); err == nil {
+		return address.Address
+	}
+	return recipient
+}
+
+// Cancel removes a scheduled message before it is sent. It returns false if
+// the message is no longer scheduled.
+func (m *Mailer) Cancel(messageID string) (bool, error) {
+	result := database.DB.Where("message_id = ?", messageID).Delete(&models.ScheduledEmail{})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+
+	err := database.DB.Model(&models.EmailLog{}).
+		Where("message_id = ? AND status = ?", messageID, models.EmailStatusScheduled).
+		Update("status", models.EmailStatusCancelled).Error
+
+	return true, err
+}
+
+// Deliver sends a message via Postal and records the per-recipient Postal IDs
+func (m *Mailer) Deliver(msg *Message) (*postal.SendEmailResponse, error) {
+	resp, err := m.postalClient.SendEmail(msg.Request)
+	if err != nil {
+		return nil, err
+	}
+
+	for recipient, info := range resp.Data.Messages {
+		err := database.DB.Model(&models.EmailLog{}).
+			Where(`message_id = ? AND LOWER("to") = ? AND status IN ?`,
+				msg.MessageID, strings.ToLower(recipient), models.StatusesBefore(models.EmailStatusSent)).
+			Updates(map[string]interface{}{
+				"postal_message_id": strconv.Itoa(info.ID),
+				"postal_token":      info.Token,
+				"status":            models.EmailStatusSent,
+			}).Error
+		if err != nil {
+			log.Printf("Failed to update email log for %s: %v", recipient, err)
+		}
+	}
+
+	return resp, nil
+}
+
+// MarkFailed records a delivery failure on all rows of a message still waiting to be sent
+func (m *Mailer) MarkFailed(messageID string, cause error) {
+	err := database.DB.Model(&models.EmailLog{}).
+		Where("message_id = ? AND status IN ?", messageID, models.StatusesBefore(models.EmailStatusSent)).
+		Updates(map[string]interface{}{
+			"status":        models.EmailStatusFailed,
+			"error_message": cause.Error(),
+		}).Error
+	if err != nil {
+		log.Printf("Failed to mark message %s as failed: %v", messageID, err)
+	}
+}
+
+// HandleJob is the send queue worker handler
+func (m *Mailer) HandleJob(ctx context.Context, job *queue.Job) error {
+	var msg Message
+	if err := json.Unmarshal(job.Payload, &msg); err != nil {
+		return queue.Permanent(fmt.Errorf("invalid send job payload: %w", err))
+	}
+
+	_, err := m.Deliver(&msg)
+
+	var apiErr *postal.APIError
+	if errors.As(err, &apiErr) {
+		return queue.Permanent(err)
+	}
+
+	return err
+}
+
+// HandleDeadJob marks a message failed once its job is dead-lettered
+func (m *Mailer) HandleDeadJob(ctx context.Context, job *queue.Job, err error) {
+	var msg Message
+	if json.Unmarshal(job.Payload, &msg) == nil {
+		m.MarkFailed(msg.MessageID, err)
+	}
+}
@@ -0,0 +1,20 @@
+package metrics
+
+import (
+	"expvar"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Postal webhook verification counters, keyed by outcome
+var PostalWebhooks = expvar.NewMap("postal_webhooks")
+
+// Handler exposes the service's own counters as JSON. It does not serve the
+// whole expvar registry, which includes the command line and memory stats.
+func Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Content-Type", "application/json; charset=utf-8")
+		c.String(http.StatusOK, `{"postal_webhooks": %s}`, PostalWebhooks.String())
+	}
+}
@@ -1,10 +1,13 @@
 package middleware
 
 import (
+	"bytes"
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"time"
 
@@ -24,7 +27,16 @@ func NewAPIKeyMiddleware(redisClient *redis.Client) *APIKeyMiddleware {
 	}
 }
 
+// CostFunc returns how many units a request counts against the rate limit
+type CostFunc func(c *gin.Context) int
+
 func (m *APIKeyMiddleware) Validate() gin.HandlerFunc {
+	return m.ValidateWithCost(nil)
+}
+
+// ValidateWithCost is like Validate but charges the rate limit by the request's
+// cost, e.g. the number of messages in a batch. A nil cost charges 1.
+func (m *APIKeyMiddleware) ValidateWithCost(cost CostFunc) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		apiKey := c.GetHeader("X-API-Key")
 		if apiKey == "" {
@@ -45,7 +57,11 @@ func (m *APIKeyMiddleware) Validate() gin.HandlerFunc {
 		}
 
 		// Check rate limit
-		if !m.checkRateLimit(c.Request.Context(), key.ID, key.RateLimit) {
+		units := 1
+		if cost != nil {
+			units = cost(c)
+		}
+		if !m.checkRateLimit(c.Request.Context(), key.ID, key.RateLimit, units) {
 			c.JSON(http.StatusTooManyRequests, gin.H{"error": "Rate limit exceeded"})
 			c.Abort()
 			return
@@ -62,7 +78,7 @@ func (m *APIKeyMiddleware) Validate() gin.HandlerFunc {
 	}
 }
 
-func (m *APIKeyMiddleware) checkRateLimit(ctx context.Context, keyID uint, limit int) bool {
+func (m *APIKeyMiddleware) checkRateLimit(ctx context.Context, keyID uint, limit, units int) bool {
 	if m.redisClient == nil {
 		return true // Skip rate limiting if Redis is not configured
 	}
@@ -75,19 +91,43 @@ func (m *APIKeyMiddleware) checkRateLimit(ctx context.Context, keyID uint, limit
 		return true // Allow on error
 	}
 
-	if count >= limit {
+	if count+units > limit {
 		return false
 	}
 
 	// Increment counter
 	pipe := m.redisClient.Pipeline()
-	pipe.Incr(ctx, key)
+	pipe.IncrBy(ctx, key, int64(units))
 	pipe.Expire(ctx, key, time.Hour)
 	_, err = pipe.Exec(ctx)
 
 	return err == nil
 }
 
+// BatchCost charges a batch send by its number of messages. Batches that are
+// empty, malformed or too large count as 1 and are rejected by the handler.
+func BatchCost(maxMessages int) CostFunc {
+	return func(c *gin.Context) int {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			return 1
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		var batch struct {
+			Messages []json.RawMessage `json:"messages"`
+		}
+		if err := json.Unmarshal(body, &batch); err != nil {
+			return 1
+		}
+
+		if len(batch.Messages) < 1 || len(batch.Messages) > maxMessages {
+			return 1
+		}
+		return len(batch.Messages)
+	}
+}
+
 func hashAPIKey(key string) string {
 	hash := sha256.Sum256([]byte(key))
 	return hex.EncodeToString(hash[:])
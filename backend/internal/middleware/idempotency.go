@@ -0,0 +1,175 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// idempotencyTTL is how long a completed response is replayed for
+	idempotencyTTL = 24 * time.Hour
+
+	// idempotencyLockTTL bounds how long an in-progress request holds its key
+	idempotencyLockTTL = 2 * time.Minute
+
+	maxIdempotencyKeyLength = 255
+)
+
+type IdempotencyMiddleware struct {
+	redisClient *redis.Client
+}
+
+func NewIdempotencyMiddleware(redisClient *redis.Client) *IdempotencyMiddleware {
+	return &IdempotencyMiddleware{
+		redisClient: redisClient,
+	}
+}
+
+// idempotencyRecord is stored in Redis for each key
+type idempotencyRecord struct {
+	Fingerprint string `json:"fingerprint"`
+	Pending     bool   `json:"pending"`
+	Status      int    `json:"status,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	Body        string `json:"body,omitempty"`
+}
+
+// responseRecorder keeps a copy of the response body
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Handle honours the Idempotency-Key header. Keys are scoped per API key, so it
+// must run after Validate. A repeated request with the same body replays the
+// stored response; a different body is rejected with 409.
+func (m *IdempotencyMiddleware) Handle() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		idempotencyKey := c.GetHeader("Idempotency-Key")
+		if idempotencyKey == "" || m.redisClient == nil {
+			c.Next() // Skip if no key was sent or Redis is not configured
+			return
+		}
+
+		if len(idempotencyKey) > maxIdempotencyKeyLength {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key must be at most 255 characters"})
+			c.Abort()
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		ctx := c.Request.Context()
+		key := idempotencyRedisKey(c.GetUint("apiKeyID"), idempotencyKey)
+		fingerprint := requestFingerprint(c.Request.Method, c.FullPath(), body)
+
+		pending, _ := json.Marshal(idempotencyRecord{Fingerprint: fingerprint, Pending: true})
+		acquired, err := m.redisClient.SetNX(ctx, key, pending, idempotencyLockTTL).Result()
+		if err != nil {
+			log.Printf("Idempotency check failed, processing request normally: %v", err)
+			c.Next()
+			return
+		}
+
+		if !acquired {
+			m.replay(c, key, fingerprint)
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+
+		// Only successful responses are replayed; anything else frees the key
+		// so the client can retry once the problem is fixed
+		status := recorder.Status()
+		if status < 200 || status >= 300 {
+			m.redisClient.Del(context.Background(), key)
+			return
+		}
+
+		record, _ := json.Marshal(idempotencyRecord{
+			Fingerprint: fingerprint,
+			Status:      status,
+			ContentType: recorder.Header().Get("Content-Type"),
+			Body:        recorder.body.String(),
+		})
+		if err := m.redisClient.Set(context.Background(), key, record, idempotencyTTL).Err(); err != nil {
+			log.Printf("Failed to store idempotent response: %v", err)
+		}
+	}
+}
+
+// replay answers a request whose key was already used
+func (m *IdempotencyMiddleware) replay(c *gin.Context, key, fingerprint string) {
+	raw, err := m.redisClient.Get(c.Request.Context(), key).Result()
+	if err != nil {
+		// The key expired or was released in the meantime
+		c.JSON(http.StatusConflict, gin.H{"error": "A request with this Idempotency-Key is already in progress"})
+		c.Abort()
+		return
+	}
+
+	var record idempotencyRecord
+	if err := json.Unmarshal([]byte(raw), &record); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read stored response"})
+		c.Abort()
+		return
+	}
+
+	if record.Fingerprint != fingerprint {
+		c.JSON(http.StatusConflict, gin.H{"error": "Idempotency-Key was already used with a different request"})
+		c.Abort()
+		return
+	}
+
+	if record.Pending {
+		c.JSON(http.StatusConflict, gin.H{"error": "A request with this Idempotency-Key is already in progress"})
+		c.Abort()
+		return
+	}
+
+	c.Header("Idempotent-Replayed", "true")
+	c.Data(record.Status, record.ContentType, []byte(record.Body))
+	c.Abort()
+}
+
+func idempotencyRedisKey(apiKeyID uint, idempotencyKey string) string {
+	hash := sha256.Sum256([]byte(idempotencyKey))
+	return fmt.Sprintf("idempotency:apikey:%d:%s", apiKeyID, hex.EncodeToString(hash[:]))
+}
+
+// requestFingerprint identifies the request a key was first used with
+func requestFingerprint(method, path string, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(method + " " + path + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
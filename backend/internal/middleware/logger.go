@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// secretQueryParams are query parameters that carry credentials, such as the
+// feedback token Postal sends to /inbound/feedback
+var secretQueryParams = []string{"token"}
+
+// Logger is gin's request logger with secret query parameters redacted
+func Logger() gin.HandlerFunc {
+	return gin.LoggerWithFormatter(func(param gin.LogFormatterParams) string {
+		param.Path = redactQuery(param.Path)
+
+		var statusColor, methodColor, resetColor string
+		if param.IsOutputColor() {
+			statusColor = param.StatusCodeColor()
+			methodColor = param.MethodColor()
+			resetColor = param.ResetColor()
+		}
+
+		if param.Latency > time.Minute {
+			param.Latency = param.Latency.Truncate(time.Second)
+		}
+		return fmt.Sprintf("[GIN] %v |%s %3d %s| %13v | %15s |%s %-7s %s %#v\n%s",
+			param.TimeStamp.Format("2006/01/02 - 15:04:05"),
+			statusColor, param.StatusCode, resetColor,
+			param.Latency,
+			param.ClientIP,
+			methodColor, param.Method, resetColor,
+			param.Path,
+			param.ErrorMessage,
+		)
+	})
+}
+
+// redactQuery replaces the values of secret query parameters in a logged path
+func redactQuery(path string) string {
+	base, rawQuery, found := strings.Cut(path, "?")
+	if !found {
+		return path
+	}
+
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		// Do not log a query that cannot be checked
+		return base + "?[unparsable query]"
+	}
+
+	redacted := false
+	for _, name := range secretQueryParams {
+		for key := range query {
+			if strings.EqualFold(key, name) {
+				query[key] = []string{"REDACTED"}
+				redacted = true
+			}
+		}
+	}
+	if !redacted {
+		return path
+	}
+
+	return base + "?" + query.Encode()
+}
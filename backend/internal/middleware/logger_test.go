@@ -0,0 +1,23 @@
+package middleware
+
+import "testing"
+
+func TestRedactQuery(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/inbound/feedback", "/inbound/feedback"},
+		{"/inbound/feedback?token=s3cret", "/inbound/feedback?token=REDACTED"},
+		{"/inbound/feedback?Token=s3cret&x=1", "/inbound/feedback?Token=REDACTED&x=1"},
+		{"/inbound/feedback?token=a&token=b", "/inbound/feedback?token=REDACTED"},
+		{"/api/emails?page=2&limit=10", "/api/emails?page=2&limit=10"},
+		{"/inbound/feedback?token=%zz", "/inbound/feedback?[unparsable query]"},
+	}
+
+	for _, tt := range tests {
+		if got := redactQuery(tt.path); got != tt.want {
+			t.Errorf("redactQuery(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
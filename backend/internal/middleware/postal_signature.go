@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rsa"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shohag/seentics-email/internal/config"
+	"github.com/shohag/seentics-email/internal/metrics"
+	"github.com/shohag/seentics-email/internal/postal"
+)
+
+type PostalSignatureMiddleware struct {
+	publicKey *rsa.PublicKey
+	secret    string
+}
+
+func NewPostalSignatureMiddleware(cfg *config.Config) (*PostalSignatureMiddleware, error) {
+	m := &PostalSignatureMiddleware{
+		secret: cfg.PostalWebhookSecret,
+	}
+
+	if cfg.PostalWebhookPublicKey != "" {
+		publicKey, err := postal.ParsePublicKey(cfg.PostalWebhookPublicKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid POSTAL_WEBHOOK_PUBLIC_KEY: %w", err)
+		}
+		m.publicKey = publicKey
+	}
+
+	switch {
+	case m.publicKey == nil && m.secret == "":
+		log.Println("Warning: Postal webhook verification is not configured, all Postal webhooks will be rejected")
+	case m.publicKey == nil:
+		log.Println("Warning: only POSTAL_WEBHOOK_SECRET is set; Postal signs webhooks with RSA, so webhooks not re-signed by a proxy will be rejected")
+	}
+
+	return m, nil
+}
+
+// Verify rejects Postal webhooks that are unsigned or carry an invalid signature.
+// The body is restored so handlers can read it again.
+func (m *PostalSignatureMiddleware) Verify() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		signature := c.GetHeader(postal.HeaderSignature)
+		signature256 := c.GetHeader(postal.HeaderSignature256)
+
+		if signature == "" && signature256 == "" {
+			metrics.PostalWebhooks.Add("unsigned", 1)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing webhook signature"})
+			c.Abort()
+			return
+		}
+
+		if !m.valid(body, signature, signature256) {
+			metrics.PostalWebhooks.Add("invalid", 1)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid webhook signature"})
+			c.Abort()
+			return
+		}
+
+		metrics.PostalWebhooks.Add("verified", 1)
+		c.Next()
+	}
+}
+
+// valid checks the signature with the configured scheme, preferring RSA
+func (m *PostalSignatureMiddleware) valid(body []byte, signature, signature256 string) bool {
+	if m.publicKey != nil {
+		if signature256 != "" {
+			return postal.VerifyWebhookRSASignature(body, signature256, crypto.SHA256, m.publicKey)
+		}
+		return postal.VerifyWebhookRSASignature(body, signature, crypto.SHA1, m.publicKey)
+	}
+
+	if m.secret != "" {
+		return postal.VerifyWebhookSignature(body, signature, m.secret)
+	}
+
+	return false
+}
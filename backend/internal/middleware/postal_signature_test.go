@@ -0,0 +1,176 @@
+package middleware
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shohag/seentics-email/internal/config"
+	"github.com/shohag/seentics-email/internal/metrics"
+	"github.com/shohag/seentics-email/internal/postal"
+)
+
+func signBody(t *testing.T, key *rsa.PrivateKey, hash crypto.Hash, body string) string {
+	t.Helper()
+	var digest []byte
+	if hash == crypto.SHA1 {
+		sum := sha1.Sum([]byte(body))
+		digest = sum[:]
+	} else {
+		sum := sha256.Sum256([]byte(body))
+		digest = sum[:]
+	}
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, hash, digest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(sig)
+}
+
+func counter(name string) int64 {
+	if v, ok := metrics.PostalWebhooks.Get(name).(*expvar.Int); ok {
+		return v.Value()
+	}
+	return 0
+}
+
+func newSignatureRouter(t *testing.T, cfg *config.Config) *gin.Engine {
+	t.Helper()
+	m, err := NewPostalSignatureMiddleware(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/webhooks/postal", m.Verify(), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return router
+}
+
+func TestPostalSignatureMiddleware(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router := newSignatureRouter(t, &config.Config{PostalWebhookPublicKey: base64.StdEncoding.EncodeToString(der)})
+	body := `{"event":"MessageDelivered","uuid":"5f1c","payload":{}}`
+
+	tests := []struct {
+		name        string
+		body        string
+		headers     map[string]string
+		wantStatus  int
+		wantCounter string
+	}{
+		{
+			name:        "SHA-1 signature",
+			body:        body,
+			headers:     map[string]string{postal.HeaderSignature: signBody(t, key, crypto.SHA1, body)},
+			wantStatus:  http.StatusOK,
+			wantCounter: "verified",
+		},
+		{
+			name:        "SHA-256 signature",
+			body:        body,
+			headers:     map[string]string{postal.HeaderSignature256: signBody(t, key, crypto.SHA256, body)},
+			wantStatus:  http.StatusOK,
+			wantCounter: "verified",
+		},
+		{
+			name: "SHA-256 signature is preferred",
+			body: body,
+			headers: map[string]string{
+				postal.HeaderSignature:    "bm90IHZhbGlk",
+				postal.HeaderSignature256: signBody(t, key, crypto.SHA256, body),
+			},
+			wantStatus:  http.StatusOK,
+			wantCounter: "verified",
+		},
+		{
+			name:        "tampered body",
+			body:        strings.Replace(body, "Delivered", "Bounced", 1),
+			headers:     map[string]string{postal.HeaderSignature: signBody(t, key, crypto.SHA1, body)},
+			wantStatus:  http.StatusUnauthorized,
+			wantCounter: "invalid",
+		},
+		{
+			name:        "wrong key",
+			body:        body,
+			headers:     map[string]string{postal.HeaderSignature256: signBody(t, otherKey, crypto.SHA256, body)},
+			wantStatus:  http.StatusUnauthorized,
+			wantCounter: "invalid",
+		},
+		{
+			name:        "missing signature",
+			body:        body,
+			wantStatus:  http.StatusUnauthorized,
+			wantCounter: "unsigned",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := counter(tt.wantCounter)
+
+			req := httptest.NewRequest(http.MethodPost, "/webhooks/postal", strings.NewReader(tt.body))
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if got := counter(tt.wantCounter) - before; got != 1 {
+				t.Errorf("%s counter increased by %d, want 1", tt.wantCounter, got)
+			}
+		})
+	}
+}
+
+func TestPostalSignatureMiddlewareSecret(t *testing.T) {
+	const secret = "proxy-secret"
+	router := newSignatureRouter(t, &config.Config{PostalWebhookSecret: secret})
+	body := `{"event":"MessageSent"}`
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+
+	for signature, want := range map[string]int{
+		hex.EncodeToString(mac.Sum(nil)): http.StatusOK,
+		"0000":                           http.StatusUnauthorized,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/webhooks/postal", strings.NewReader(body))
+		req.Header.Set(postal.HeaderSignature, signature)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != want {
+			t.Errorf("signature %q: status = %d, want %d", signature, rec.Code, want)
+		}
+	}
+}
@@ -12,6 +12,8 @@ const (
 	DomainStatusPending  DomainStatus = "pending"
 	DomainStatusVerified DomainStatus = "verified"
 	DomainStatusFailed   DomainStatus = "failed"
+	DomainStatusDegraded DomainStatus = "degraded" // Was verified, but its records have since drifted
+	DomainStatusExpired  DomainStatus = "expired"  // Stayed pending for too long
 )
 
 type Domain struct {
@@ -22,6 +24,11 @@ type Domain struct {
 	PostalServerID     string         `json:"postal_server_id"`              // Postal's server ID
 	PostalOrganization string         `json:"postal_organization"`           // Postal's organization
 	DNSRecords         string         `gorm:"type:jsonb" json:"dns_records"` // JSON array of DNS records
+	DKIMSelector       string         `json:"dkim_selector"`
+	DKIMPrivateKey     string         `gorm:"type:text" json:"-"`                                 // PEM private key, AES-GCM encrypted
+	VerificationChecks string         `gorm:"type:jsonb;default:'[]'" json:"verification_checks"` // JSON array of per-record results of the last check
+	LastCheckedAt      *time.Time     `json:"last_checked_at,omitempty"`
+	StatusChangedAt    *time.Time     `json:"status_changed_at,omitempty"`
 	CreatedAt          time.Time      `json:"created_at"`
 	UpdatedAt          time.Time      `json:"updated_at"`
 	DeletedAt          gorm.DeletedAt `gorm:"index" json:"-"`
@@ -9,6 +9,8 @@ import (
 type EmailStatus string
 
 const (
+	EmailStatusScheduled EmailStatus = "scheduled"
+	EmailStatusCancelled EmailStatus = "cancelled"
 	EmailStatusQueued    EmailStatus = "queued"
 	EmailStatusSent      EmailStatus = "sent"
 	EmailStatusDelivered EmailStatus = "delivered"
@@ -17,23 +19,73 @@ const (
 	EmailStatusComplaint EmailStatus = "complaint"
 )
 
+// emailStatusRank orders statuses so that late or replayed events never move
+// an email back to an earlier state, e.g. from delivered back to sent
+var emailStatusRank = map[EmailStatus]int{
+	EmailStatusScheduled: 0,
+	EmailStatusQueued:    0,
+	EmailStatusSent:      1,
+	EmailStatusDelivered: 2,
+	EmailStatusBounced:   3,
+	EmailStatusFailed:    3,
+	EmailStatusCancelled: 3,
+	EmailStatusComplaint: 4,
+}
+
+// StatusesBefore returns the statuses an email may advance from to reach s
+func StatusesBefore(s EmailStatus) []EmailStatus {
+	var statuses []EmailStatus
+	for status, rank := range emailStatusRank {
+		if rank < emailStatusRank[s] {
+			statuses = append(statuses, status)
+		}
+	}
+	return statuses
+}
+
+// BounceType classifies why a message bounced
+type BounceType string
+
+const (
+	BounceTypeHard        BounceType = "hard"         // The address does not exist
+	BounceTypeSoft        BounceType = "soft"         // A temporary failure
+	BounceTypeBlock       BounceType = "block"        // Rejected by policy, e.g. spam filtering
+	BounceTypeMailboxFull BounceType = "mailbox_full" // The mailbox is over quota
+)
+
+// RecipientType is how a recipient was addressed on the original message
+type RecipientType string
+
+const (
+	RecipientTypeTo  RecipientType = "to"
+	RecipientTypeCC  RecipientType = "cc"
+	RecipientTypeBCC RecipientType = "bcc"
+)
+
 type EmailLog struct {
-	ID              uint           `gorm:"primaryKey" json:"id"`
-	UserID          uint           `gorm:"not null;index" json:"user_id"`
-	MessageID       string         `gorm:"uniqueIndex;not null" json:"message_id"`
-	PostalMessageID string         `gorm:"index" json:"postal_message_id"`
-	From            string         `gorm:"not null" json:"from"`
-	To              string         `gorm:"not null;index" json:"to"`
-	Subject         string         `json:"subject"`
-	Status          EmailStatus    `gorm:"default:'queued';index" json:"status"`
-	ErrorMessage    string         `json:"error_message,omitempty"`
-	OpenedAt        *time.Time     `json:"opened_at,omitempty"`
-	ClickedAt       *time.Time     `json:"clicked_at,omitempty"`
-	BouncedAt       *time.Time     `json:"bounced_at,omitempty"`
-	DeliveredAt     *time.Time     `json:"delivered_at,omitempty"`
-	CreatedAt       time.Time      `json:"created_at"`
-	UpdatedAt       time.Time      `json:"updated_at"`
-	DeletedAt       gorm.DeletedAt `gorm:"index" json:"-"`
+	ID               uint           `gorm:"primaryKey" json:"id"`
+	UserID           uint           `gorm:"not null;index" json:"user_id"`
+	MessageID        string         `gorm:"index:idx_email_logs_message;not null" json:"message_id"`
+	PostalMessageID  string         `gorm:"index" json:"postal_message_id"` // Per-recipient Postal message ID
+	PostalToken      string         `gorm:"index" json:"postal_token"`      // Per-recipient Postal message token
+	From             string         `gorm:"not null" json:"from"`
+	To               string         `gorm:"not null;index" json:"to"` // Bare recipient address
+	RecipientType    RecipientType  `gorm:"not null;default:'to'" json:"recipient_type"`
+	Subject          string         `json:"subject"`
+	Status           EmailStatus    `gorm:"default:'queued';index" json:"status"`
+	ErrorMessage     string         `json:"error_message,omitempty"`
+	BounceType       BounceType     `json:"bounce_type,omitempty"`
+	BounceDiagnostic string         `json:"bounce_diagnostic,omitempty"`                // SMTP diagnostic from the bounce
+	Attachments      string         `gorm:"type:jsonb;default:'[]'" json:"attachments"` // JSON array of attachment names and sizes
+	TemplateID       *uint          `gorm:"index" json:"template_id,omitempty"`
+	OpenedAt         *time.Time     `json:"opened_at,omitempty"`
+	ClickedAt        *time.Time     `json:"clicked_at,omitempty"`
+	BouncedAt        *time.Time     `json:"bounced_at,omitempty"`
+	ComplainedAt     *time.Time     `json:"complained_at,omitempty"`
+	DeliveredAt      *time.Time     `json:"delivered_at,omitempty"`
+	CreatedAt        time.Time      `json:"created_at"`
+	UpdatedAt        time.Time      `json:"updated_at"`
+	DeletedAt        gorm.DeletedAt `gorm:"index" json:"-"`
 
 	// Relationships
 	User User `gorm:"foreignKey:UserID" json:"-"`
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+)
+
+// Notification types
+const (
+	NotificationWebhookDisabled = "webhook.disabled"
+)
+
+// Notification is an internal message shown to the account in the dashboard
+type Notification struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"not null;index" json:"user_id"`
+	Type      string     `gorm:"not null" json:"type"`
+	Title     string     `gorm:"not null" json:"title"`
+	Message   string     `gorm:"type:text" json:"message"`
+	ReadAt    *time.Time `json:"read_at,omitempty"`
+	CreatedAt time.Time  `gorm:"index" json:"created_at"`
+
+	// Relationships
+	User User `gorm:"foreignKey:UserID" json:"-"`
+}
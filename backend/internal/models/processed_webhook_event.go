@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+)
+
+// ProcessedWebhookEventTTL is how long processed Postal event UUIDs are
+// remembered, well beyond the period over which Postal retries an event
+const ProcessedWebhookEventTTL = 7 * 24 * time.Hour
+
+// ProcessedWebhookEvent remembers Postal webhook UUIDs that were already
+// handled when Redis is not available
+type ProcessedWebhookEvent struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UUID      string    `gorm:"uniqueIndex;not null" json:"uuid"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+)
+
+// ScheduledEmail holds a message until its send time. The row is removed
+// when the message is handed off for delivery or cancelled.
+type ScheduledEmail struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uint      `gorm:"not null;index" json:"user_id"`
+	MessageID string    `gorm:"uniqueIndex;not null" json:"message_id"`
+	SendAt    time.Time `gorm:"not null;index" json:"send_at"`
+	Payload   string    `gorm:"type:jsonb;not null" json:"-"` // Serialized mailer message
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// Relationships
+	User User `gorm:"foreignKey:UserID" json:"-"`
+}
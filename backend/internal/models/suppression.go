@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+type SuppressionReason string
+
+const (
+	SuppressionReasonBounce    SuppressionReason = "bounce"
+	SuppressionReasonComplaint SuppressionReason = "complaint"
+	SuppressionReasonManual    SuppressionReason = "manual"
+)
+
+// Suppression blocks sending to an address on behalf of a user
+type Suppression struct {
+	ID         uint              `gorm:"primaryKey" json:"id"`
+	UserID     uint              `gorm:"not null;uniqueIndex:idx_suppressions_email" json:"user_id"`
+	Email      string            `gorm:"not null;uniqueIndex:idx_suppressions_email" json:"email"` // Stored lowercase
+	Reason     SuppressionReason `gorm:"not null" json:"reason"`
+	Details    string            `json:"details,omitempty"`
+	EmailLogID *uint             `json:"email_log_id,omitempty"` // Email whose bounce or complaint caused it
+	CreatedAt  time.Time         `json:"created_at"`
+	UpdatedAt  time.Time         `json:"updated_at"`
+
+	// Relationships
+	User User `gorm:"foreignKey:UserID" json:"-"`
+}
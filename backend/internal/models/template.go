@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Template struct {
+	ID             uint           `gorm:"primaryKey" json:"id"`
+	UserID         uint           `gorm:"not null;index" json:"user_id"`
+	Name           string         `gorm:"not null" json:"name"`
+	Description    string         `json:"description"`
+	CurrentVersion int            `gorm:"not null;default:1" json:"current_version"` // Version used when sending
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"`
+
+	// Relationships
+	User User `gorm:"foreignKey:UserID" json:"-"`
+}
+
+// TemplateVersion is an immutable revision of a template's content.
+// Editing a template's content creates a new version.
+type TemplateVersion struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	TemplateID uint      `gorm:"not null;uniqueIndex:idx_template_versions_version" json:"template_id"`
+	Version    int       `gorm:"not null;uniqueIndex:idx_template_versions_version" json:"version"`
+	Subject    string    `gorm:"not null" json:"subject"`
+	HTMLBody   string    `gorm:"type:text" json:"html_body"`
+	PlainBody  string    `gorm:"type:text" json:"plain_body"`
+	CreatedAt  time.Time `json:"created_at"`
+
+	// Relationships
+	Template Template `gorm:"foreignKey:TemplateID" json:"-"`
+}
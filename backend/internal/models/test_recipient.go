@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// TestRecipient is an address that may receive template test sends
+type TestRecipient struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_test_recipients_email" json:"user_id"`
+	Email     string    `gorm:"not null;uniqueIndex:idx_test_recipients_email" json:"email"` // Stored lowercase
+	CreatedAt time.Time `json:"created_at"`
+
+	// Relationships
+	User User `gorm:"foreignKey:UserID" json:"-"`
+}
@@ -18,6 +18,16 @@ type Webhook struct {
 	UpdatedAt       time.Time      `json:"updated_at"`
 	DeletedAt       gorm.DeletedAt `gorm:"index" json:"-"`
 
+	// Secret rotation: the previous secret keeps signing until it expires
+	PreviousSecret          string     `json:"-"`
+	PreviousSecretExpiresAt *time.Time `json:"previous_secret_expires_at,omitempty"`
+
+	// Circuit breaker: endpoints that keep failing are disabled automatically
+	ConsecutiveFailures int        `gorm:"default:0" json:"consecutive_failures"`
+	FailingSince        *time.Time `json:"failing_since,omitempty"`
+	DisabledReason      string     `json:"disabled_reason,omitempty"`
+	DisabledAt          *time.Time `json:"disabled_at,omitempty"`
+
 	// Relationships
 	User User `gorm:"foreignKey:UserID" json:"-"`
 }
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+)
+
+// WebhookDelivery records a single attempt to deliver an event to a webhook
+type WebhookDelivery struct {
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	WebhookID      uint      `gorm:"not null;index" json:"webhook_id"`
+	UserID         uint      `gorm:"not null;index" json:"user_id"`
+	EventID        string    `gorm:"not null;index" json:"event_id"`
+	EventType      string    `gorm:"not null" json:"event_type"`
+	Attempt        int       `gorm:"not null" json:"attempt"`
+	RequestBody    string    `gorm:"type:text" json:"request_body"`
+	ResponseStatus int       `json:"response_status"`
+	ResponseBody   string    `gorm:"type:text" json:"response_body"` // Truncated excerpt
+	LatencyMs      int64     `json:"latency_ms"`
+	Error          string    `json:"error,omitempty"`
+	Success        bool      `gorm:"index" json:"success"`
+	CreatedAt      time.Time `gorm:"index" json:"created_at"`
+
+	// Relationships
+	Webhook Webhook `gorm:"foreignKey:WebhookID" json:"-"`
+}
@@ -0,0 +1,22 @@
+package notifications
+
+import (
+	"log"
+
+	"github.com/shohag/seentics-email/internal/database"
+	"github.com/shohag/seentics-email/internal/models"
+)
+
+// Notify raises an internal notification for an account
+func Notify(userID uint, notificationType, title, message string) {
+	notification := models.Notification{
+		UserID:  userID,
+		Type:    notificationType,
+		Title:   title,
+		Message: message,
+	}
+
+	if err := database.DB.Create(&notification).Error; err != nil {
+		log.Printf("Failed to create notification for user %d: %v", userID, err)
+	}
+}
@@ -0,0 +1,168 @@
+package postal
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// BounceInfo is the SMTP outcome reported for a bounced message
+type BounceInfo struct {
+	Code       int    // SMTP reply code, e.g. 550
+	Status     string // Enhanced status code (RFC 3463), e.g. 5.1.1
+	Diagnostic string // Diagnostic text from the receiving server
+}
+
+var (
+	// Codes must stand on their own, so that parts of IP addresses such as
+	// [203.0.113.5] or 10.5.1.12 are not mistaken for them
+	smtpCodePattern     = regexp.MustCompile(`(?:^|[\s:;(<\[])([245]\d\d)(?:[\s-]|$)`)
+	enhancedCodePattern = regexp.MustCompile(`(?:^|[^\d.])([245]\.\d{1,3}\.\d{1,3})(?:[^\d.]|$)`)
+
+	// fieldNamePattern matches DSN field names such as Diagnostic-Code
+	fieldNamePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9-]*$`)
+)
+
+// GetBounceFromPayload extracts what a bounce event says about the failure,
+// and the ID of Postal's bounce message, whose body holds the full DSN when
+// the event itself carries no diagnostic text
+func GetBounceFromPayload(payload map[string]interface{}) (string, BounceInfo) {
+	var bounceID string
+	sources := []map[string]interface{}{payload}
+	if bounce, ok := payload["bounce"].(map[string]interface{}); ok {
+		bounceID = stringValue(bounce["id"])
+		sources = append(sources, bounce)
+	}
+
+	for _, source := range sources {
+		for _, key := range []string{"details", "output", "diagnostic"} {
+			if text := strings.TrimSpace(stringValue(source[key])); text != "" {
+				return bounceID, ParseDiagnostic(text)
+			}
+		}
+	}
+
+	return bounceID, BounceInfo{}
+}
+
+// ParseDSN reads a delivery status notification (RFC 3464) and returns the
+// status and diagnostic of its first failed recipient. Bodies that are not
+// structured DSNs are scanned for the first line with an SMTP reply code.
+func ParseDSN(body string) BounceInfo {
+	var info BounceInfo
+	var fallback string
+
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var field, value string
+	flush := func() {
+		switch strings.ToLower(field) {
+		case "status":
+			if info.Status == "" {
+				info.Status = findCode(enhancedCodePattern, value)
+			}
+		case "diagnostic-code":
+			if info.Diagnostic == "" {
+				// Diagnostic-Code: smtp; 550 5.1.1 User unknown
+				if _, text, ok := strings.Cut(value, ";"); ok {
+					value = text
+				}
+				info.Diagnostic = strings.TrimSpace(value)
+			}
+		}
+		field, value = "", ""
+	}
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		// Folded header lines continue the previous field
+		if field != "" && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			value += " " + strings.TrimSpace(line)
+			continue
+		}
+		flush()
+
+		if name, rest, ok := strings.Cut(line, ":"); ok && fieldNamePattern.MatchString(name) {
+			field, value = name, strings.TrimSpace(rest)
+			continue
+		}
+
+		if fallback == "" && smtpCodePattern.MatchString(line) {
+			fallback = strings.TrimSpace(line)
+		}
+	}
+	flush()
+
+	if info.Diagnostic == "" {
+		info.Diagnostic = fallback
+	}
+
+	parsed := ParseDiagnostic(info.Diagnostic)
+	parsed.Diagnostic = info.Diagnostic
+	if info.Status != "" {
+		parsed.Status = info.Status
+	}
+	return parsed
+}
+
+// ParseDiagnostic extracts the SMTP reply code and enhanced status code from
+// a diagnostic such as "550 5.1.1 <bob@example.com>: Recipient address rejected"
+func ParseDiagnostic(text string) BounceInfo {
+	info := BounceInfo{Diagnostic: strings.TrimSpace(text)}
+
+	info.Status = findCode(enhancedCodePattern, text)
+	if match := findCode(smtpCodePattern, text); match != "" {
+		info.Code, _ = strconv.Atoi(match)
+	}
+
+	return info
+}
+
+// findCode returns the first code captured by pattern, or an empty string
+func findCode(pattern *regexp.Regexp, text string) string {
+	if match := pattern.FindStringSubmatch(text); match != nil {
+		return match[1]
+	}
+	return ""
+}
+
+// GetMessagePlainBody retrieves the plain-text body of a message, such as a
+// bounce message received by Postal
+func (c *Client) GetMessagePlainBody(messageID string) (string, error) {
+	payload := map[string]interface{}{
+		"id":          messageID,
+		"_expansions": []string{"plain_body"},
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	resp, err := c.doRequest("POST", "/api/v1/messages/message", body)
+	if err != nil {
+		return "", err
+	}
+
+	var result struct {
+		Status string `json:"status"`
+		Data   struct {
+			PlainBody string `json:"plain_body"`
+		} `json:"data"`
+	}
+
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return "", fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	if result.Status != "success" {
+		return "", fmt.Errorf("postal API error")
+	}
+
+	return result.Data.PlainBody, nil
+}
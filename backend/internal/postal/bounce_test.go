@@ -0,0 +1,117 @@
+package postal
+
+import "testing"
+
+const postfixDSN = `This is the mail system at host mail.example.net.
+
+I'm sorry to have to inform you that your message could not
+be delivered to one or more recipients.
+
+<bob@example.com>: host mx.example.com[203.0.113.5] said: 550 5.1.1
+    <bob@example.com>: Recipient address rejected: User unknown in virtual
+    mailbox table (in reply to RCPT TO command)
+
+Reporting-MTA: dns; mail.example.net
+X-Postfix-Queue-ID: 4F2A81C0123
+Arrival-Date: Mon,  3 Jun 2024 10:00:00 +0000 (UTC)
+
+Final-Recipient: rfc822; bob@example.com
+Original-Recipient: rfc822;bob@example.com
+Action: failed
+Status: 5.1.1
+Remote-MTA: dns; mx.example.com
+Diagnostic-Code: smtp; 550 5.1.1 <bob@example.com>: Recipient address
+    rejected: User unknown in virtual mailbox table
+`
+
+const gmailQuotaDSN = `** Message blocked **
+
+Your message to alice@gmail.com couldn't be delivered.
+
+Final-Recipient: rfc822; alice@gmail.com
+Action: failed
+Status: 4.2.2 (mailbox full)
+Remote-MTA: dns; gmail-smtp-in.l.google.com. (142.250.153.27, the server for the domain gmail.com.)
+Diagnostic-Code: smtp; 452-4.2.2 The email account that you tried to reach is over quota.
+`
+
+const eximBounce = `This message was created automatically by mail delivery software.
+
+A message that you sent could not be delivered to one or more of its
+recipients. This is a permanent error. The following address(es) failed:
+
+  bob@example.com
+    host mx.example.com [203.0.113.5]
+    SMTP error from remote mail server after RCPT TO:<bob@example.com>:
+    550 5.1.1 No such user
+`
+
+func TestParseDSN(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want BounceInfo
+	}{
+		{
+			name: "Postfix DSN with folded diagnostic",
+			body: postfixDSN,
+			want: BounceInfo{
+				Code:       550,
+				Status:     "5.1.1",
+				Diagnostic: "550 5.1.1 <bob@example.com>: Recipient address rejected: User unknown in virtual mailbox table",
+			},
+		},
+		{
+			name: "status with comment",
+			body: gmailQuotaDSN,
+			want: BounceInfo{
+				Code:       452,
+				Status:     "4.2.2",
+				Diagnostic: "452-4.2.2 The email account that you tried to reach is over quota.",
+			},
+		},
+		{
+			name: "unstructured Exim bounce",
+			body: eximBounce,
+			want: BounceInfo{
+				Code:       550,
+				Status:     "5.1.1",
+				Diagnostic: "550 5.1.1 No such user",
+			},
+		},
+		{
+			name: "no diagnostic",
+			body: "Your message could not be delivered.",
+			want: BounceInfo{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseDSN(tt.body); got != tt.want {
+				t.Errorf("ParseDSN() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDiagnostic(t *testing.T) {
+	tests := []struct {
+		text       string
+		wantCode   int
+		wantStatus string
+	}{
+		{"550 5.1.1 User unknown", 550, "5.1.1"},
+		{"550-5.7.1 [203.0.113.5] blocked", 550, "5.7.1"},
+		{"host mx.example.com[203.0.113.5] said: 421 try later", 421, ""},
+		{"relay 10.5.1.12 refused: 554 5.7.1 rejected", 554, "5.7.1"},
+		{"Connection timed out", 0, ""},
+	}
+
+	for _, tt := range tests {
+		info := ParseDiagnostic(tt.text)
+		if info.Code != tt.wantCode || info.Status != tt.wantStatus {
+			t.Errorf("ParseDiagnostic(%q) = %d %q, want %d %q", tt.text, info.Code, info.Status, tt.wantCode, tt.wantStatus)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package postal
 
 import (
 	"bytes"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -28,7 +29,10 @@ func NewClient(baseURL, apiKey string) *Client {
 // SendEmailRequest represents the request to send an email
 type SendEmailRequest struct {
 	To          []string          `json:"to"`
+	CC          []string          `json:"cc,omitempty"`
+	BCC         []string          `json:"bcc,omitempty"`
 	From        string            `json:"from"`
+	ReplyTo     string            `json:"reply_to,omitempty"`
 	Subject     string            `json:"subject"`
 	HTMLBody    string            `json:"html_body,omitempty"`
 	PlainBody   string            `json:"plain_body,omitempty"`
@@ -39,7 +43,8 @@ type SendEmailRequest struct {
 type Attachment struct {
 	Name        string `json:"name"`
 	ContentType string `json:"content_type"`
-	Data        string `json:"data"` // Base64 encoded
+	Data        string `json:"data"`                 // Base64 encoded
+	ContentID   string `json:"content_id,omitempty"` // Set for inline images referenced as cid:<content_id>
 }
 
 // SendEmailResponse represents the response from Postal
@@ -61,14 +66,28 @@ type MessageInfo struct {
 	Token string `json:"token"`
 }
 
-// SendEmail sends an email via Postal
+// SendEmail sends an email via Postal. Messages with inline attachments are
+// built as raw MIME, since Postal's message API cannot set Content-IDs.
 func (c *Client) SendEmail(req SendEmailRequest) (*SendEmailResponse, error) {
+	if hasInlineAttachments(req.Attachments) {
+		return c.SendRawEmail(req)
+	}
+
 	payload := map[string]interface{}{
 		"to":      req.To,
 		"from":    req.From,
 		"subject": req.Subject,
 	}
 
+	if len(req.CC) > 0 {
+		payload["cc"] = req.CC
+	}
+	if len(req.BCC) > 0 {
+		payload["bcc"] = req.BCC
+	}
+	if req.ReplyTo != "" {
+		payload["reply_to"] = req.ReplyTo
+	}
 	if req.HTMLBody != "" {
 		payload["html_body"] = req.HTMLBody
 	}
@@ -92,18 +111,61 @@ func (c *Client) SendEmail(req SendEmailRequest) (*SendEmailResponse, error) {
 		return nil, err
 	}
 
+	return parseSendResponse(resp)
+}
+
+// SendRawEmail builds a MIME message from the request and sends it via
+// Postal's raw message API
+func (c *Client) SendRawEmail(req SendEmailRequest) (*SendEmailResponse, error) {
+	raw, err := BuildMIMEMessage(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build message: %w", err)
+	}
+
+	payload := map[string]interface{}{
+		"mail_from": envelopeAddress(req.From),
+		"rcpt_to":   envelopeRecipients(req),
+		"data":      base64.StdEncoding.EncodeToString(raw),
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	resp, err := c.doRequest("POST", "/api/v1/send/raw", body)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseSendResponse(resp)
+}
+
+// parseSendResponse decodes the response of Postal's send APIs
+func parseSendResponse(resp []byte) (*SendEmailResponse, error) {
 	var result SendEmailResponse
 	if err := json.Unmarshal(resp, &result); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
 	}
 
 	if result.Status != "success" {
-		return nil, fmt.Errorf("postal API error: %v", result.Messages)
+		return nil, &APIError{Status: result.Status, Messages: result.Messages}
 	}
 
 	return &result, nil
 }
 
+// APIError is returned when Postal accepts the request but rejects the message.
+// Retrying the same request will not succeed.
+type APIError struct {
+	Status   string
+	Messages []string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("postal API error (%s): %v", e.Status, e.Messages)
+}
+
 // GetMessageDetails retrieves details about a sent message
 type MessageDetails struct {
 	ID               int        `json:"id"`
@@ -150,6 +212,44 @@ func (c *Client) GetMessage(messageID string) (*MessageDetails, error) {
 	return &result.Data, nil
 }
 
+// RegisterDomainRequest hands a domain's own DKIM key to Postal
+type RegisterDomainRequest struct {
+	Name         string `json:"name"`
+	DKIMSelector string `json:"dkim_selector"`
+	DKIMKey      string `json:"dkim_private_key"` // PEM-encoded RSA private key
+}
+
+// RegisterDomain adds a domain to the Postal server with the given DKIM key,
+// or replaces the key if the domain already exists, so that Postal signs the
+// domain's mail with the key its DNS publishes
+func (c *Client) RegisterDomain(req RegisterDomainRequest) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	resp, err := c.doRequest("POST", "/api/v1/domains/create", body)
+	if err != nil {
+		return err
+	}
+
+	var result struct {
+		Status string `json:"status"`
+		Data   struct {
+			Message string `json:"message"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	if result.Status != "success" {
+		return &APIError{Status: result.Status, Messages: []string{result.Data.Message}}
+	}
+
+	return nil
+}
+
 // doRequest performs an HTTP request to Postal API
 func (c *Client) doRequest(method, path string, body []byte) ([]byte, error) {
 	url := c.BaseURL + path
@@ -0,0 +1,46 @@
+package postal
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+)
+
+// InboundMessage is a message received by Postal and passed to an HTTP
+// endpoint route in the "raw message" format
+type InboundMessage struct {
+	ID       interface{} `json:"id"`
+	RcptTo   string      `json:"rcpt_to"`
+	MailFrom string      `json:"mail_from"`
+	Token    string      `json:"token"`
+	Message  string      `json:"message"` // The raw RFC 5322 message
+	Base64   bool        `json:"base64"`  // Whether Message is base64 encoded
+}
+
+// ParseInboundMessage parses an inbound message delivered by Postal
+func ParseInboundMessage(data []byte) (*InboundMessage, error) {
+	var msg InboundMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return nil, fmt.Errorf("failed to parse inbound message: %w", err)
+	}
+	if msg.Message == "" {
+		return nil, fmt.Errorf("inbound message has no raw message")
+	}
+	return &msg, nil
+}
+
+// Raw returns the raw RFC 5322 message
+func (m *InboundMessage) Raw() ([]byte, error) {
+	if !m.Base64 {
+		return []byte(m.Message), nil
+	}
+	return base64.StdEncoding.DecodeString(m.Message)
+}
+
+// Key returns an identifier that is stable across Postal's retries
+func (m *InboundMessage) Key() string {
+	if m.Token != "" {
+		return m.Token
+	}
+	return stringValue(m.ID)
+}
@@ -0,0 +1,239 @@
+package postal
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"sort"
+	"strings"
+	"time"
+)
+
+// mimePart is a rendered MIME entity: its headers and encoded body
+type mimePart struct {
+	header textproto.MIMEHeader
+	body   []byte
+}
+
+// BuildMIMEMessage renders a send request as an RFC 5322 message. The layout is
+//
+//	multipart/mixed            (when there are regular attachments)
+//	  multipart/related        (when there are inline images)
+//	    multipart/alternative  (when there are both plain and HTML bodies)
+func BuildMIMEMessage(req SendEmailRequest) ([]byte, error) {
+	var inline, attached []mimePart
+	for _, attachment := range req.Attachments {
+		part, err := attachmentPart(attachment)
+		if err != nil {
+			return nil, err
+		}
+		if attachment.ContentID != "" {
+			inline = append(inline, part)
+		} else {
+			attached = append(attached, part)
+		}
+	}
+
+	root, err := bodyPart(req)
+	if err != nil {
+		return nil, err
+	}
+	if len(inline) > 0 {
+		if root, err = multipartPart("related", append([]mimePart{root}, inline...)); err != nil {
+			return nil, err
+		}
+	}
+	if len(attached) > 0 {
+		if root, err = multipartPart("mixed", append([]mimePart{root}, attached...)); err != nil {
+			return nil, err
+		}
+	}
+
+	// Custom headers go first so the standard headers always replace them.
+	// Empty values are not written, which drops Bcc, Sender and Message-ID.
+	headers := make(map[string]string, len(req.Headers)+10)
+	for name, value := range req.Headers {
+		name = textproto.CanonicalMIMEHeaderKey(name)
+		if strings.HasPrefix(name, "Content-") {
+			continue
+		}
+		headers[name] = value
+	}
+	standard := map[string]string{
+		"From":         req.From,
+		"Sender":       "",
+		"To":           strings.Join(req.To, ", "),
+		"Cc":           strings.Join(req.CC, ", "),
+		"Bcc":          "",
+		"Reply-To":     req.ReplyTo,
+		"Subject":      mime.QEncoding.Encode("utf-8", req.Subject),
+		"Message-ID":   "",
+		"Date":         time.Now().Format(time.RFC1123Z),
+		"MIME-Version": "1.0",
+	}
+	for name, value := range standard {
+		delete(headers, textproto.CanonicalMIMEHeaderKey(name))
+		headers[name] = value
+	}
+	for name := range root.header {
+		headers[name] = root.header.Get(name)
+	}
+
+	var buf bytes.Buffer
+	if err := writeHeaders(&buf, headers); err != nil {
+		return nil, err
+	}
+	buf.WriteString("\r\n")
+	buf.Write(root.body)
+
+	return buf.Bytes(), nil
+}
+
+// bodyPart renders the plain and/or HTML bodies
+func bodyPart(req SendEmailRequest) (mimePart, error) {
+	if req.PlainBody != "" && req.HTMLBody != "" {
+		plain, err := textPart("text/plain", req.PlainBody)
+		if err != nil {
+			return mimePart{}, err
+		}
+		html, err := textPart("text/html", req.HTMLBody)
+		if err != nil {
+			return mimePart{}, err
+		}
+		return multipartPart("alternative", []mimePart{plain, html})
+	}
+
+	if req.HTMLBody != "" {
+		return textPart("text/html", req.HTMLBody)
+	}
+	return textPart("text/plain", req.PlainBody)
+}
+
+// textPart renders a quoted-printable text part
+func textPart(contentType, body string) (mimePart, error) {
+	var encoded bytes.Buffer
+	qp := quotedprintable.NewWriter(&encoded)
+	if _, err := qp.Write([]byte(body)); err != nil {
+		return mimePart{}, err
+	}
+	if err := qp.Close(); err != nil {
+		return mimePart{}, err
+	}
+
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", contentType+"; charset=utf-8")
+	header.Set("Content-Transfer-Encoding", "quoted-printable")
+	return mimePart{header: header, body: encoded.Bytes()}, nil
+}
+
+// attachmentPart renders a base64 attachment, inline if it has a Content-ID
+func attachmentPart(attachment Attachment) (mimePart, error) {
+	data, err := base64.StdEncoding.DecodeString(attachment.Data)
+	if err != nil {
+		return mimePart{}, fmt.Errorf("invalid attachment data for %s: %w", attachment.Name, err)
+	}
+	if strings.ContainsAny(attachment.ContentType+attachment.ContentID, "\r\n<>") {
+		return mimePart{}, fmt.Errorf("invalid attachment headers for %s", attachment.Name)
+	}
+
+	disposition := "attachment"
+	header := textproto.MIMEHeader{}
+	if attachment.ContentID != "" {
+		disposition = "inline"
+		header.Set("Content-ID", "<"+attachment.ContentID+">")
+	}
+	header.Set("Content-Type", attachment.ContentType)
+	header.Set("Content-Transfer-Encoding", "base64")
+	header.Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": attachment.Name}))
+
+	// Wrap at 76 characters as required by RFC 2045
+	encoded := base64.StdEncoding.EncodeToString(data)
+	var body bytes.Buffer
+	for len(encoded) > 76 {
+		body.WriteString(encoded[:76] + "\r\n")
+		encoded = encoded[76:]
+	}
+	body.WriteString(encoded + "\r\n")
+
+	return mimePart{header: header, body: body.Bytes()}, nil
+}
+
+// multipartPart wraps parts in a multipart container of the given subtype
+func multipartPart(subtype string, parts []mimePart) (mimePart, error) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for _, part := range parts {
+		w, err := writer.CreatePart(part.header)
+		if err != nil {
+			return mimePart{}, err
+		}
+		if _, err := w.Write(part.body); err != nil {
+			return mimePart{}, err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return mimePart{}, err
+	}
+
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", fmt.Sprintf("multipart/%s; boundary=%s", subtype, writer.Boundary()))
+	return mimePart{header: header, body: body.Bytes()}, nil
+}
+
+// writeHeaders writes the top-level message headers, rejecting header injection
+func writeHeaders(buf *bytes.Buffer, headers map[string]string) error {
+	keys := make([]string, 0, len(headers))
+	for key := range headers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := headers[key]
+		if strings.ContainsAny(key+value, "\r\n") {
+			return fmt.Errorf("invalid header %q", key)
+		}
+		if value == "" {
+			continue
+		}
+		fmt.Fprintf(buf, "%s: %s\r\n", key, value)
+	}
+
+	return nil
+}
+
+// hasInlineAttachments reports whether any attachment is embedded by Content-ID
+func hasInlineAttachments(attachments []Attachment) bool {
+	for _, attachment := range attachments {
+		if attachment.ContentID != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// envelopeAddress extracts the bare address from a header value such as
+// "Acme <billing@acme.com>"
+func envelopeAddress(value string) string {
+	if addr, err := mail.ParseAddress(value); err == nil {
+		return addr.Address
+	}
+	return value
+}
+
+// envelopeRecipients lists the SMTP recipients of a request, including BCC
+// recipients which never appear in the headers
+func envelopeRecipients(req SendEmailRequest) []string {
+	recipients := make([]string, 0, len(req.To)+len(req.CC)+len(req.BCC))
+	for _, list := range [][]string{req.To, req.CC, req.BCC} {
+		for _, recipient := range list {
+			recipients = append(recipients, envelopeAddress(recipient))
+		}
+	}
+	return recipients
+}
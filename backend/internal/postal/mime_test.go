@@ -0,0 +1,332 @@
+package postal
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// parsedPart is a decoded MIME entity with its children
+type parsedPart struct {
+	mediaType string
+	header    map[string][]string
+	body      []byte
+	parts     []parsedPart
+}
+
+// parseEntity decodes a MIME entity, descending into multipart containers
+func parseEntity(t *testing.T, header map[string][]string, body io.Reader) parsedPart {
+	t.Helper()
+
+	get := func(name string) string {
+		if values := header[name]; len(values) > 0 {
+			return values[0]
+		}
+		return ""
+	}
+
+	mediaType, params, err := mime.ParseMediaType(get("Content-Type"))
+	if err != nil {
+		t.Fatalf("invalid Content-Type %q: %v", get("Content-Type"), err)
+	}
+	part := parsedPart{mediaType: mediaType, header: header}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		reader := multipart.NewReader(body, params["boundary"])
+		for {
+			child, err := reader.NextRawPart()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("failed to read %s part: %v", mediaType, err)
+			}
+			part.parts = append(part.parts, parseEntity(t, child.Header, child))
+		}
+		return part
+	}
+
+	raw, err := io.ReadAll(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	switch get("Content-Transfer-Encoding") {
+	case "base64":
+		for _, line := range strings.Split(strings.TrimSpace(string(raw)), "\r\n") {
+			if len(line) > 76 {
+				t.Errorf("base64 line of %d characters exceeds 76", len(line))
+			}
+		}
+		decoded, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(string(raw), "\r\n", ""))
+		if err != nil {
+			t.Fatalf("invalid base64 body: %v", err)
+		}
+		part.body = decoded
+	case "quoted-printable":
+		decoded, err := io.ReadAll(quotedprintable.NewReader(bytes.NewReader(raw)))
+		if err != nil {
+			t.Fatalf("invalid quoted-printable body: %v", err)
+		}
+		part.body = decoded
+	default:
+		part.body = raw
+	}
+	return part
+}
+
+func parseMessage(t *testing.T, raw []byte) (*mail.Message, parsedPart) {
+	t.Helper()
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("failed to parse message: %v\n%s", err, raw)
+	}
+	return msg, parseEntity(t, msg.Header, msg.Body)
+}
+
+func TestBuildMIMEMessageStructure(t *testing.T) {
+	logo := bytes.Repeat([]byte{0x89, 'P', 'N', 'G', 0x00, 0xff}, 100)
+	invoice := []byte("%PDF-1.4 invoice")
+
+	raw, err := BuildMIMEMessage(SendEmailRequest{
+		From:      "Acme <billing@acme.example>",
+		To:        []string{"Jane <jane@example.com>"},
+		CC:        []string{"ops@example.com"},
+		BCC:       []string{"audit@example.com"},
+		Subject:   "Your invoice — März",
+		PlainBody: "See the attached invoice.",
+		HTMLBody:  `<p>See the attached invoice.</p><img src="cid:logo">`,
+		Attachments: []Attachment{
+			{Name: "logo.png", ContentType: "image/png", Data: base64.StdEncoding.EncodeToString(logo), ContentID: "logo"},
+			{Name: "invoice.pdf", ContentType: "application/pdf", Data: base64.StdEncoding.EncodeToString(invoice)},
+		},
+	})
+	if err != nil {
+		t.Fatalf("BuildMIMEMessage: %v", err)
+	}
+
+	msg, root := parseMessage(t, raw)
+
+	if got := msg.Header.Get("From"); got != "Acme <billing@acme.example>" {
+		t.Errorf("From = %q", got)
+	}
+	if got := msg.Header.Get("To"); got != "Jane <jane@example.com>" {
+		t.Errorf("To = %q", got)
+	}
+	if got := msg.Header.Get("Cc"); got != "ops@example.com" {
+		t.Errorf("Cc = %q", got)
+	}
+	if _, ok := msg.Header["Bcc"]; ok {
+		t.Error("Bcc header must not be written")
+	}
+	if bytes.Contains(raw, []byte("audit@example.com")) {
+		t.Error("BCC address leaked into the message")
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil || subject != "Your invoice — März" {
+		t.Errorf("Subject = %q (%v)", subject, err)
+	}
+	if msg.Header.Get("MIME-Version") != "1.0" || msg.Header.Get("Date") == "" {
+		t.Errorf("missing MIME-Version or Date: %v", msg.Header)
+	}
+
+	// mixed → [related → [alternative → [plain, html], logo], invoice]
+	if root.mediaType != "multipart/mixed" || len(root.parts) != 2 {
+		t.Fatalf("root = %s with %d parts", root.mediaType, len(root.parts))
+	}
+	related, attachment := root.parts[0], root.parts[1]
+	if related.mediaType != "multipart/related" || len(related.parts) != 2 {
+		t.Fatalf("related = %s with %d parts", related.mediaType, len(related.parts))
+	}
+	alternative, inline := related.parts[0], related.parts[1]
+	if alternative.mediaType != "multipart/alternative" || len(alternative.parts) != 2 {
+		t.Fatalf("alternative = %s with %d parts", alternative.mediaType, len(alternative.parts))
+	}
+
+	plain, html := alternative.parts[0], alternative.parts[1]
+	if plain.mediaType != "text/plain" || string(plain.body) != "See the attached invoice." {
+		t.Errorf("plain part = %s %q", plain.mediaType, plain.body)
+	}
+	if html.mediaType != "text/html" || !strings.Contains(string(html.body), `src="cid:logo"`) {
+		t.Errorf("html part = %s %q", html.mediaType, html.body)
+	}
+
+	if got := inline.header["Content-Id"]; !reflect.DeepEqual(got, []string{"<logo>"}) {
+		t.Errorf("inline Content-ID = %q", got)
+	}
+	if !strings.HasPrefix(inline.header["Content-Disposition"][0], "inline") || !bytes.Equal(inline.body, logo) {
+		t.Errorf("inline part = %q, %d bytes", inline.header["Content-Disposition"], len(inline.body))
+	}
+
+	disposition, params, err := mime.ParseMediaType(attachment.header["Content-Disposition"][0])
+	if err != nil || disposition != "attachment" || params["filename"] != "invoice.pdf" {
+		t.Errorf("attachment disposition = %q", attachment.header["Content-Disposition"])
+	}
+	if attachment.mediaType != "application/pdf" || !bytes.Equal(attachment.body, invoice) {
+		t.Errorf("attachment = %s %q", attachment.mediaType, attachment.body)
+	}
+}
+
+func TestBuildMIMEMessageSinglePart(t *testing.T) {
+	raw, err := BuildMIMEMessage(SendEmailRequest{
+		From:     "billing@acme.example",
+		To:       []string{"jane@example.com"},
+		Subject:  "Hi",
+		HTMLBody: "<p>Hi</p>",
+	})
+	if err != nil {
+		t.Fatalf("BuildMIMEMessage: %v", err)
+	}
+
+	msg, root := parseMessage(t, raw)
+	if root.mediaType != "text/html" || string(root.body) != "<p>Hi</p>" {
+		t.Errorf("root = %s %q", root.mediaType, root.body)
+	}
+	if _, ok := msg.Header["Cc"]; ok {
+		t.Error("empty Cc header written")
+	}
+}
+
+func TestBuildMIMEMessageCustomHeaders(t *testing.T) {
+	raw, err := BuildMIMEMessage(SendEmailRequest{
+		From:      "billing@acme.example",
+		To:        []string{"jane@example.com"},
+		BCC:       []string{"audit@example.com"},
+		Subject:   "Hi",
+		PlainBody: "Hello",
+		Headers: map[string]string{
+			"X-Campaign":                "october",
+			"from":                      "attacker@evil.example",
+			"TO":                        "victim@example.com",
+			"Subject":                   "Overridden",
+			"Bcc":                       "hidden@evil.example",
+			"Message-Id":                "<forged@evil.example>",
+			"Content-Type":              "text/html",
+			"content-transfer-encoding": "8bit",
+			"Content-Disposition":       "attachment",
+		},
+	})
+	if err != nil {
+		t.Fatalf("BuildMIMEMessage: %v", err)
+	}
+
+	msg, root := parseMessage(t, raw)
+
+	want := map[string]string{
+		"From":                      "billing@acme.example",
+		"To":                        "jane@example.com",
+		"Subject":                   "Hi",
+		"X-Campaign":                "october",
+		"Content-Type":              "text/plain; charset=utf-8",
+		"Content-Transfer-Encoding": "quoted-printable",
+	}
+	for name, value := range want {
+		if got := msg.Header[name]; !reflect.DeepEqual(got, []string{value}) {
+			t.Errorf("%s = %q, want %q", name, got, value)
+		}
+	}
+	for _, name := range []string{"Bcc", "Message-Id", "Content-Disposition"} {
+		if got, ok := msg.Header[name]; ok {
+			t.Errorf("%s header must not be written, got %q", name, got)
+		}
+	}
+	if string(root.body) != "Hello" {
+		t.Errorf("body = %q", root.body)
+	}
+}
+
+func TestBuildMIMEMessageRejectsHeaderInjection(t *testing.T) {
+	base := func() SendEmailRequest {
+		return SendEmailRequest{From: "billing@acme.example", To: []string{"jane@example.com"}, Subject: "Hi", PlainBody: "Hello"}
+	}
+
+	tests := map[string]func(*SendEmailRequest){
+		"custom header value": func(r *SendEmailRequest) { r.Headers = map[string]string{"X-Campaign": "a\r\nBcc: victim@example.com"} },
+		"custom header name":  func(r *SendEmailRequest) { r.Headers = map[string]string{"X-A\r\nBcc": "victim@example.com"} },
+		"bare LF":             func(r *SendEmailRequest) { r.Headers = map[string]string{"X-Campaign": "a\nBcc: victim@example.com"} },
+		"from":                func(r *SendEmailRequest) { r.From = "billing@acme.example\r\nBcc: victim@example.com" },
+		"reply-to":            func(r *SendEmailRequest) { r.ReplyTo = "a@acme.example\nBcc: victim@example.com" },
+		"attachment id": func(r *SendEmailRequest) {
+			r.Attachments = []Attachment{{Name: "a.png", ContentType: "image/png", ContentID: "a>\r\nX: y"}}
+		},
+	}
+
+	for name, mutate := range tests {
+		t.Run(name, func(t *testing.T) {
+			req := base()
+			mutate(&req)
+			if raw, err := BuildMIMEMessage(req); err == nil {
+				t.Errorf("expected an error, got\n%s", raw)
+			}
+		})
+	}
+
+	// The subject is encoded, so a line break cannot start a new header
+	req := base()
+	req.Subject = "Hi\r\nBcc: victim@example.com"
+	raw, err := BuildMIMEMessage(req)
+	if err != nil {
+		t.Fatalf("BuildMIMEMessage: %v", err)
+	}
+	if msg, _ := parseMessage(t, raw); len(msg.Header["Bcc"]) > 0 {
+		t.Error("line break in the subject injected a header")
+	}
+}
+
+func TestSendRawEmailEnvelope(t *testing.T) {
+	var payload struct {
+		MailFrom string   `json:"mail_from"`
+		RcptTo   []string `json:"rcpt_to"`
+		Data     string   `json:"data"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/send/raw" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("invalid payload: %v", err)
+		}
+		w.Write([]byte(`{"status":"success","data":{"message_id":"abc","messages":{}}}`))
+	}))
+	defer server.Close()
+
+	_, err := NewClient(server.URL, "key").SendRawEmail(SendEmailRequest{
+		From:      "Acme <billing@acme.example>",
+		To:        []string{"Jane <jane@example.com>"},
+		CC:        []string{"ops@example.com"},
+		BCC:       []string{"Audit <audit@example.com>"},
+		Subject:   "Hi",
+		PlainBody: "Hello",
+		Attachments: []Attachment{
+			{Name: "logo.png", ContentType: "image/png", Data: base64.StdEncoding.EncodeToString([]byte("png")), ContentID: "logo"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("SendRawEmail: %v", err)
+	}
+
+	if payload.MailFrom != "billing@acme.example" {
+		t.Errorf("mail_from = %q", payload.MailFrom)
+	}
+	if want := []string{"jane@example.com", "ops@example.com", "audit@example.com"}; !reflect.DeepEqual(payload.RcptTo, want) {
+		t.Errorf("rcpt_to = %q, want %q", payload.RcptTo, want)
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(payload.Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, _ := parseMessage(t, raw)
+	if _, ok := msg.Header["Bcc"]; ok || bytes.Contains(raw, []byte("audit@example.com")) {
+		t.Error("BCC recipient appears in the message")
+	}
+}
@@ -1,22 +1,46 @@
 package postal
 
 import (
+	"crypto"
 	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha1"
 	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
+	"encoding/pem"
 	"fmt"
+	"math"
+	"strconv"
+	"strings"
 	"time"
 )
 
+// Signature headers sent by Postal with every webhook
+const (
+	HeaderSignature    = "X-Postal-Signature"     // Base64 RSA-SHA1 signature of the body
+	HeaderSignature256 = "X-Postal-Signature-256" // Base64 RSA-SHA256 signature of the body
+)
+
 // WebhookEvent represents an event from Postal
 type WebhookEvent struct {
 	Event     string                 `json:"event"`
-	Timestamp time.Time              `json:"timestamp"`
+	Timestamp float64                `json:"timestamp"` // Unix time with fractional seconds
 	UUID      string                 `json:"uuid"`
 	Payload   map[string]interface{} `json:"payload"`
 }
 
+// OccurredAt returns when Postal raised the event, or the zero time if unknown
+func (e *WebhookEvent) OccurredAt() time.Time {
+	if e.Timestamp <= 0 {
+		return time.Time{}
+	}
+	sec, frac := math.Modf(e.Timestamp)
+	return time.Unix(int64(sec), int64(frac*1e9))
+}
+
 // Common webhook event types
 const (
 	EventMessageSent      = "MessageSent"
@@ -36,6 +60,66 @@ func VerifyWebhookSignature(payload []byte, signature, secret string) bool {
 	return hmac.Equal([]byte(signature), []byte(expectedMAC))
 }
 
+// VerifyWebhookRSASignature verifies a base64 RSA PKCS#1 v1.5 signature of a
+// webhook payload. Postal signs with SHA-1 in X-Postal-Signature and, on
+// newer versions, with SHA-256 in X-Postal-Signature-256.
+func VerifyWebhookRSASignature(payload []byte, signature string, hash crypto.Hash, publicKey *rsa.PublicKey) bool {
+	if publicKey == nil || signature == "" {
+		return false
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(signature))
+	if err != nil {
+		return false
+	}
+
+	var digest []byte
+	switch hash {
+	case crypto.SHA1:
+		sum := sha1.Sum(payload)
+		digest = sum[:]
+	case crypto.SHA256:
+		sum := sha256.Sum256(payload)
+		digest = sum[:]
+	default:
+		return false
+	}
+
+	return rsa.VerifyPKCS1v15(publicKey, hash, digest, sig) == nil
+}
+
+// ParsePublicKey parses Postal's webhook public key. It accepts a PEM block or
+// the bare base64 DER value shown in the Postal web interface.
+func ParsePublicKey(key string) (*rsa.PublicKey, error) {
+	key = strings.TrimSpace(key)
+
+	var der []byte
+	if block, _ := pem.Decode([]byte(key)); block != nil {
+		der = block.Bytes
+	} else {
+		decoded, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(key), ""))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode public key: %w", err)
+		}
+		der = decoded
+	}
+
+	parsed, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		if rsaKey, rsaErr := x509.ParsePKCS1PublicKey(der); rsaErr == nil {
+			return rsaKey, nil
+		}
+		return nil, fmt.Errorf("failed to parse public key: %w", err)
+	}
+
+	rsaKey, ok := parsed.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("public key is not an RSA key")
+	}
+
+	return rsaKey, nil
+}
+
 // ParseWebhookEvent parses a webhook event from JSON
 func ParseWebhookEvent(data []byte) (*WebhookEvent, error) {
 	var event WebhookEvent
@@ -45,15 +129,55 @@ func ParseWebhookEvent(data []byte) (*WebhookEvent, error) {
 	return &event, nil
 }
 
+// MessageRef identifies the message a webhook event refers to
+type MessageRef struct {
+	ID        string // Postal's per-recipient message ID
+	Token     string // Postal's per-recipient message token
+	MessageID string // Message-ID header, shared by all recipients
+	To        string // Recipient address
+}
+
+// GetMessageRefFromPayload extracts the message reference from a webhook payload.
+// Bounce events describe the original message under "original_message".
+func GetMessageRefFromPayload(payload map[string]interface{}) MessageRef {
+	var ref MessageRef
+
+	msg, ok := payload["message"].(map[string]interface{})
+	if !ok {
+		msg, ok = payload["original_message"].(map[string]interface{})
+	}
+	if ok {
+		ref.ID = stringValue(msg["id"])
+		ref.Token = stringValue(msg["token"])
+		ref.MessageID = stringValue(msg["message_id"])
+		ref.To = stringValue(msg["to"])
+	}
+
+	if ref.MessageID == "" {
+		ref.MessageID = stringValue(payload["message_id"])
+	}
+
+	return ref
+}
+
 // GetMessageIDFromPayload extracts the message ID from webhook payload
 func GetMessageIDFromPayload(payload map[string]interface{}) string {
-	if msgID, ok := payload["message_id"].(string); ok {
-		return msgID
+	ref := GetMessageRefFromPayload(payload)
+	if ref.ID != "" {
+		return ref.ID
 	}
-	if msg, ok := payload["message"].(map[string]interface{}); ok {
-		if msgID, ok := msg["id"].(string); ok {
-			return msgID
-		}
+	return ref.MessageID
+}
+
+// stringValue converts JSON strings and numbers to a string
+func stringValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case json.Number:
+		return v.String()
 	}
 	return ""
 }
@@ -0,0 +1,134 @@
+package postal
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"strings"
+	"testing"
+)
+
+func generateKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func sign(t *testing.T, key *rsa.PrivateKey, hash crypto.Hash, body []byte) string {
+	t.Helper()
+	var digest []byte
+	if hash == crypto.SHA1 {
+		sum := sha1.Sum(body)
+		digest = sum[:]
+	} else {
+		sum := sha256.Sum256(body)
+		digest = sum[:]
+	}
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, hash, digest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(sig)
+}
+
+func TestVerifyWebhookRSASignature(t *testing.T) {
+	key := generateKey(t)
+	otherKey := generateKey(t)
+	body := []byte(`{"event":"MessageDelivered","uuid":"5f1c","payload":{}}`)
+
+	tests := []struct {
+		name      string
+		body      []byte
+		signature string
+		hash      crypto.Hash
+		publicKey *rsa.PublicKey
+		want      bool
+	}{
+		{"SHA-1", body, sign(t, key, crypto.SHA1, body), crypto.SHA1, &key.PublicKey, true},
+		{"SHA-256", body, sign(t, key, crypto.SHA256, body), crypto.SHA256, &key.PublicKey, true},
+		{"surrounding whitespace", body, " " + sign(t, key, crypto.SHA256, body) + "\n", crypto.SHA256, &key.PublicKey, true},
+		{"tampered body", []byte(`{"event":"MessageBounced","uuid":"5f1c","payload":{}}`), sign(t, key, crypto.SHA1, body), crypto.SHA1, &key.PublicKey, false},
+		{"wrong key", body, sign(t, otherKey, crypto.SHA256, body), crypto.SHA256, &key.PublicKey, false},
+		{"SHA-1 signature checked as SHA-256", body, sign(t, key, crypto.SHA1, body), crypto.SHA256, &key.PublicKey, false},
+		{"not base64", body, "not-a-signature!", crypto.SHA1, &key.PublicKey, false},
+		{"empty signature", body, "", crypto.SHA1, &key.PublicKey, false},
+		{"no key", body, sign(t, key, crypto.SHA1, body), crypto.SHA1, nil, false},
+		{"unsupported hash", body, sign(t, key, crypto.SHA256, body), crypto.SHA512, &key.PublicKey, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VerifyWebhookRSASignature(tt.body, tt.signature, tt.hash, tt.publicKey); got != tt.want {
+				t.Errorf("VerifyWebhookRSASignature = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePublicKey(t *testing.T) {
+	key := generateKey(t)
+
+	pkix, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkcs1 := x509.MarshalPKCS1PublicKey(&key.PublicKey)
+	bare := base64.StdEncoding.EncodeToString(pkix)
+
+	// Postal shows the key as bare base64, which is often pasted with line breaks
+	var wrapped strings.Builder
+	for i := 0; i < len(bare); i += 64 {
+		wrapped.WriteString(bare[i:min(i+64, len(bare))] + "\n")
+	}
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecDER, err := x509.MarshalPKIXPublicKey(&ecKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		key     string
+		wantErr bool
+	}{
+		{name: "PEM public key", key: string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pkix}))},
+		{name: "PEM RSA public key", key: string(pem.EncodeToMemory(&pem.Block{Type: "RSA PUBLIC KEY", Bytes: pkcs1}))},
+		{name: "bare base64", key: bare},
+		{name: "bare base64 with line breaks", key: "\n" + wrapped.String()},
+		{name: "not base64", key: "not a key!", wantErr: true},
+		{name: "base64 of garbage", key: base64.StdEncoding.EncodeToString([]byte("garbage")), wantErr: true},
+		{name: "not an RSA key", key: base64.StdEncoding.EncodeToString(ecDER), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, err := ParsePublicKey(tt.key)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParsePublicKey: %v", err)
+			}
+			if !parsed.Equal(&key.PublicKey) {
+				t.Error("parsed key differs from the original")
+			}
+		})
+	}
+}
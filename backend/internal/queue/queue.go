@@ -0,0 +1,244 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// Job is a unit of work stored in the queue
+type Job struct {
+	ID         string          `json:"id"`
+	Attempts   int             `json:"attempts"`
+	EnqueuedAt time.Time       `json:"enqueued_at"`
+	LastError  string          `json:"last_error,omitempty"`
+	Payload    json.RawMessage `json:"payload"`
+
+	raw   string // Serialized form as stored in Redis
+	lease string // Identifies this claim of the job in the in-flight set
+}
+
+// maxDeadJobs caps the dead-letter list; the oldest entries are dropped first
+const maxDeadJobs = 1000
+
+// ErrLeaseLost is returned when a job's visibility timeout passed and another
+// worker claimed it. The result of the attempt is dropped, since the job is
+// being processed again.
+var ErrLeaseLost = errors.New("job was claimed by another worker")
+
+// Queue is a reliable Redis-backed job queue. A dequeued job is kept in an
+// in-flight set until it is acknowledged; if its visibility timeout passes
+// first, it is handed to another worker. Each claim is recorded under its own
+// lease, so a worker that lost its claim cannot settle another worker's.
+type Queue struct {
+	redisClient       *redis.Client
+	name              string
+	visibilityTimeout time.Duration
+}
+
+// popScript moves the oldest pending job to the in-flight set atomically,
+// recorded as "<lease>|<raw>"
+var popScript = redis.NewScript(`
+local raw = redis.call('RPOP', KEYS[1])
+if not raw then
+	return false
+end
+redis.call('ZADD', KEYS[2], ARGV[1], ARGV[2] .. '|' .. raw)
+return raw
+`)
+
+// promoteScript moves due entries of a sorted set back to the pending list,
+// stripping the lease from in-flight entries. Entries starting with '{' are
+// plain jobs.
+var promoteScript = redis.NewScript(`
+local items = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, 100)
+for _, item in ipairs(items) do
+	redis.call('ZREM', KEYS[1], item)
+	local raw = item
+	if string.sub(item, 1, 1) ~= '{' then
+		raw = string.sub(item, string.find(item, '|', 1, true) + 1)
+	end
+	redis.call('LPUSH', KEYS[2], raw)
+end
+return #items
+`)
+
+// extendScript pushes back the deadline of a claim that is still held
+var extendScript = redis.NewScript(`
+if not redis.call('ZSCORE', KEYS[1], ARGV[2]) then
+	return 0
+end
+redis.call('ZADD', KEYS[1], ARGV[1], ARGV[2])
+return 1
+`)
+
+// settleScript ends a claim and optionally stores the job elsewhere. If the
+// claim expired but the job has not been picked up again, it is taken back
+// from the pending list. Returns 0 if another worker holds the job.
+//
+// KEYS: inflight, pending, destination (or "")
+// ARGV: lease member, raw, destination command ("", "ZADD" or "LPUSH"),
+// new raw, score for ZADD or length cap for LPUSH
+var settleScript = redis.NewScript(`
+if redis.call('ZREM', KEYS[1], ARGV[1]) == 0 and redis.call('LREM', KEYS[2], 1, ARGV[2]) == 0 then
+	return 0
+end
+if ARGV[3] == 'ZADD' then
+	redis.call('ZADD', KEYS[3], ARGV[5], ARGV[4])
+elseif ARGV[3] == 'LPUSH' then
+	redis.call('LPUSH', KEYS[3], ARGV[4])
+	redis.call('LTRIM', KEYS[3], 0, ARGV[5] - 1)
+end
+return 1
+`)
+
+func New(redisClient *redis.Client, name string, visibilityTimeout time.Duration) *Queue {
+	return &Queue{
+		redisClient:       redisClient,
+		name:              name,
+		visibilityTimeout: visibilityTimeout,
+	}
+}
+
+func (q *Queue) pendingKey() string  { return "queue:" + q.name + ":pending" }
+func (q *Queue) inflightKey() string { return "queue:" + q.name + ":inflight" }
+func (q *Queue) delayedKey() string  { return "queue:" + q.name + ":delayed" }
+func (q *Queue) deadKey() string     { return "queue:" + q.name + ":dead" }
+
+// Enqueue adds a payload to the queue
+func (q *Queue) Enqueue(ctx context.Context, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	job := &Job{
+		ID:         uuid.New().String(),
+		EnqueuedAt: time.Now().UTC(),
+		Payload:    data,
+	}
+
+	raw, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to marshal job: %w", err)
+	}
+
+	if err := q.redisClient.LPush(ctx, q.pendingKey(), raw).Err(); err != nil {
+		return fmt.Errorf("failed to enqueue job: %w", err)
+	}
+
+	return nil
+}
+
+// Dequeue claims the next pending job, or returns nil if the queue is empty
+func (q *Queue) Dequeue(ctx context.Context) (*Job, error) {
+	deadline := time.Now().Add(q.visibilityTimeout).UnixMilli()
+	lease := uuid.New().String()
+
+	raw, err := popScript.Run(ctx, q.redisClient, []string{q.pendingKey(), q.inflightKey()}, deadline, lease).Text()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to dequeue job: %w", err)
+	}
+
+	var job Job
+	if err := json.Unmarshal([]byte(raw), &job); err != nil {
+		// Drop payloads we can't decode so they don't come back forever
+		q.redisClient.ZRem(ctx, q.inflightKey(), lease+"|"+raw)
+		q.redisClient.LPush(ctx, q.deadKey(), raw)
+		q.redisClient.LTrim(ctx, q.deadKey(), 0, maxDeadJobs-1)
+		return nil, fmt.Errorf("failed to unmarshal job: %w", err)
+	}
+	job.raw = raw
+	job.lease = lease
+
+	return &job, nil
+}
+
+// Extend pushes back the visibility deadline of a job that is still being
+// processed. It returns ErrLeaseLost if the deadline had already passed and
+// the job was handed out again.
+func (q *Queue) Extend(ctx context.Context, job *Job) error {
+	deadline := time.Now().Add(q.visibilityTimeout).UnixMilli()
+	held, err := extendScript.Run(ctx, q.redisClient, []string{q.inflightKey()}, deadline, job.member()).Int()
+	if err != nil {
+		return fmt.Errorf("failed to extend job: %w", err)
+	}
+	if held == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+// Ack removes a completed job from the in-flight set
+func (q *Queue) Ack(ctx context.Context, job *Job) error {
+	return q.settle(ctx, job, "", "", "", 0)
+}
+
+// Retry schedules a failed job to run again after delay
+func (q *Queue) Retry(ctx context.Context, job *Job, delay time.Duration, cause error) error {
+	next := *job
+	next.Attempts++
+	next.LastError = cause.Error()
+
+	raw, err := json.Marshal(&next)
+	if err != nil {
+		return fmt.Errorf("failed to marshal job: %w", err)
+	}
+
+	return q.settle(ctx, job, q.delayedKey(), "ZADD", string(raw), time.Now().Add(delay).UnixMilli())
+}
+
+// DeadLetter moves a job that will not be retried to the dead-letter list,
+// which keeps the most recent maxDeadJobs entries
+func (q *Queue) DeadLetter(ctx context.Context, job *Job, cause error) error {
+	dead := *job
+	dead.Attempts++
+	dead.LastError = cause.Error()
+
+	raw, err := json.Marshal(&dead)
+	if err != nil {
+		return fmt.Errorf("failed to marshal job: %w", err)
+	}
+
+	return q.settle(ctx, job, q.deadKey(), "LPUSH", string(raw), maxDeadJobs)
+}
+
+// settle ends the job's claim and stores newRaw in destination, if given
+func (q *Queue) settle(ctx context.Context, job *Job, destination, command, newRaw string, score int64) error {
+	keys := []string{q.inflightKey(), q.pendingKey(), destination}
+	settled, err := settleScript.Run(ctx, q.redisClient, keys, job.member(), job.raw, command, newRaw, score).Int()
+	if err != nil {
+		return err
+	}
+	if settled == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+// member is the job's entry in the in-flight set
+func (j *Job) member() string {
+	return j.lease + "|" + j.raw
+}
+
+// Promote returns due retries and jobs whose visibility timeout expired to
+// the pending list. It is safe to run from several processes at once.
+func (q *Queue) Promote(ctx context.Context) error {
+	now := time.Now().UnixMilli()
+
+	for _, key := range []string{q.delayedKey(), q.inflightKey()} {
+		if err := promoteScript.Run(ctx, q.redisClient, []string{key, q.pendingKey()}, now).Err(); err != nil {
+			return fmt.Errorf("failed to promote jobs from %s: %w", key, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,300 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestQueue(t *testing.T, visibilityTimeout time.Duration) (*Queue, *miniredis.Miniredis) {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	return New(client, "test", visibilityTimeout), server
+}
+
+func mustDequeue(t *testing.T, q *Queue) *Job {
+	t.Helper()
+
+	job, err := q.Dequeue(context.Background())
+	if err != nil {
+		t.Fatalf("Dequeue: %v", err)
+	}
+	if job == nil {
+		t.Fatal("Dequeue returned no job")
+	}
+	return job
+}
+
+func assertEmpty(t *testing.T, q *Queue) {
+	t.Helper()
+
+	job, err := q.Dequeue(context.Background())
+	if err != nil {
+		t.Fatalf("Dequeue: %v", err)
+	}
+	if job != nil {
+		t.Fatalf("queue returned job %s, want empty", job.ID)
+	}
+}
+
+// expire waits until the visibility timeout of claimed jobs has passed and
+// promotes them
+func expire(t *testing.T, q *Queue) {
+	t.Helper()
+
+	time.Sleep(q.visibilityTimeout + 10*time.Millisecond)
+	if err := q.Promote(context.Background()); err != nil {
+		t.Fatalf("Promote: %v", err)
+	}
+}
+
+func TestEnqueueDequeueAck(t *testing.T) {
+	q, server := newTestQueue(t, time.Minute)
+	ctx := context.Background()
+
+	assertEmpty(t, q)
+
+	if err := q.Enqueue(ctx, map[string]string{"to": "a@example.com"}); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+
+	job := mustDequeue(t, q)
+	var payload map[string]string
+	if err := json.Unmarshal(job.Payload, &payload); err != nil || payload["to"] != "a@example.com" {
+		t.Fatalf("payload = %s (%v)", job.Payload, err)
+	}
+	if job.Attempts != 0 {
+		t.Errorf("Attempts = %d, want 0", job.Attempts)
+	}
+
+	if err := q.Ack(ctx, job); err != nil {
+		t.Fatalf("Ack: %v", err)
+	}
+	if members, _ := server.ZMembers(q.inflightKey()); len(members) != 0 {
+		t.Errorf("in-flight set = %v, want empty", members)
+	}
+	assertEmpty(t, q)
+}
+
+func TestRetryAndPromote(t *testing.T) {
+	q, _ := newTestQueue(t, time.Minute)
+	ctx := context.Background()
+
+	q.Enqueue(ctx, "payload")
+	job := mustDequeue(t, q)
+
+	if err := q.Retry(ctx, job, time.Hour, errors.New("temporary")); err != nil {
+		t.Fatalf("Retry: %v", err)
+	}
+	if err := q.Promote(ctx); err != nil {
+		t.Fatalf("Promote: %v", err)
+	}
+	assertEmpty(t, q)
+
+	q.Enqueue(ctx, "payload")
+	job = mustDequeue(t, q)
+	if err := q.Retry(ctx, job, 0, errors.New("temporary")); err != nil {
+		t.Fatalf("Retry: %v", err)
+	}
+	if err := q.Promote(ctx); err != nil {
+		t.Fatalf("Promote: %v", err)
+	}
+
+	retried := mustDequeue(t, q)
+	if retried.ID != job.ID || retried.Attempts != 1 || retried.LastError != "temporary" {
+		t.Errorf("retried job = %+v, want ID %s with 1 attempt", retried, job.ID)
+	}
+}
+
+func TestDeadLetter(t *testing.T) {
+	q, server := newTestQueue(t, time.Minute)
+	ctx := context.Background()
+
+	q.Enqueue(ctx, "payload")
+	job := mustDequeue(t, q)
+
+	if err := q.DeadLetter(ctx, job, errors.New("rejected")); err != nil {
+		t.Fatalf("DeadLetter: %v", err)
+	}
+
+	dead, err := server.List(q.deadKey())
+	if err != nil || len(dead) != 1 {
+		t.Fatalf("dead list = %v (%v), want one job", dead, err)
+	}
+	var deadJob Job
+	json.Unmarshal([]byte(dead[0]), &deadJob)
+	if deadJob.ID != job.ID || deadJob.Attempts != 1 || deadJob.LastError != "rejected" {
+		t.Errorf("dead job = %+v", deadJob)
+	}
+	assertEmpty(t, q)
+}
+
+func TestDeadLetterListIsCapped(t *testing.T) {
+	q, server := newTestQueue(t, time.Minute)
+	ctx := context.Background()
+
+	for i := 0; i < maxDeadJobs+5; i++ {
+		q.Enqueue(ctx, i)
+		if err := q.DeadLetter(ctx, mustDequeue(t, q), errors.New("rejected")); err != nil {
+			t.Fatalf("DeadLetter: %v", err)
+		}
+	}
+
+	dead, _ := server.List(q.deadKey())
+	if len(dead) != maxDeadJobs {
+		t.Fatalf("dead list has %d entries, want %d", len(dead), maxDeadJobs)
+	}
+	var newest Job
+	json.Unmarshal([]byte(dead[0]), &newest)
+	if string(newest.Payload) != "1004" {
+		t.Errorf("newest dead job payload = %s, want 1004", newest.Payload)
+	}
+}
+
+func TestExpiredJobIsRedelivered(t *testing.T) {
+	q, _ := newTestQueue(t, 50*time.Millisecond)
+
+	q.Enqueue(context.Background(), "payload")
+	job := mustDequeue(t, q)
+	expire(t, q)
+
+	redelivered := mustDequeue(t, q)
+	if redelivered.ID != job.ID {
+		t.Errorf("redelivered job %s, want %s", redelivered.ID, job.ID)
+	}
+}
+
+func TestAckTakesBackExpiredJob(t *testing.T) {
+	q, _ := newTestQueue(t, 50*time.Millisecond)
+	ctx := context.Background()
+
+	q.Enqueue(ctx, "payload")
+	job := mustDequeue(t, q)
+	expire(t, q)
+
+	// The job is pending again but no other worker has claimed it yet
+	if err := q.Ack(ctx, job); err != nil {
+		t.Fatalf("Ack: %v", err)
+	}
+	assertEmpty(t, q)
+}
+
+func TestSettleAfterReclaimIsDropped(t *testing.T) {
+	q, server := newTestQueue(t, 50*time.Millisecond)
+	ctx := context.Background()
+
+	q.Enqueue(ctx, "payload")
+	first := mustDequeue(t, q)
+	expire(t, q)
+	second := mustDequeue(t, q)
+
+	if err := q.Retry(ctx, first, 0, errors.New("temporary")); !errors.Is(err, ErrLeaseLost) {
+		t.Errorf("Retry by first worker = %v, want ErrLeaseLost", err)
+	}
+	if err := q.DeadLetter(ctx, first, errors.New("rejected")); !errors.Is(err, ErrLeaseLost) {
+		t.Errorf("DeadLetter by first worker = %v, want ErrLeaseLost", err)
+	}
+	if err := q.Ack(ctx, first); !errors.Is(err, ErrLeaseLost) {
+		t.Errorf("Ack by first worker = %v, want ErrLeaseLost", err)
+	}
+
+	// The second worker's claim is untouched
+	if err := q.Ack(ctx, second); err != nil {
+		t.Fatalf("Ack by second worker: %v", err)
+	}
+
+	if err := q.Promote(ctx); err != nil {
+		t.Fatalf("Promote: %v", err)
+	}
+	assertEmpty(t, q)
+	if server.Exists(q.deadKey()) {
+		t.Error("dead list was written by a worker that lost its claim")
+	}
+}
+
+func TestExtendPreventsRedelivery(t *testing.T) {
+	q, _ := newTestQueue(t, 100*time.Millisecond)
+	ctx := context.Background()
+
+	q.Enqueue(ctx, "payload")
+	job := mustDequeue(t, q)
+
+	time.Sleep(60 * time.Millisecond)
+	if err := q.Extend(ctx, job); err != nil {
+		t.Fatalf("Extend: %v", err)
+	}
+	time.Sleep(60 * time.Millisecond)
+	if err := q.Promote(ctx); err != nil {
+		t.Fatalf("Promote: %v", err)
+	}
+	assertEmpty(t, q)
+
+	if err := q.Ack(ctx, job); err != nil {
+		t.Fatalf("Ack: %v", err)
+	}
+}
+
+func TestExtendAfterReclaim(t *testing.T) {
+	q, _ := newTestQueue(t, 50*time.Millisecond)
+
+	q.Enqueue(context.Background(), "payload")
+	job := mustDequeue(t, q)
+	expire(t, q)
+
+	if err := q.Extend(context.Background(), job); !errors.Is(err, ErrLeaseLost) {
+		t.Errorf("Extend = %v, want ErrLeaseLost", err)
+	}
+}
+
+func TestPromoteJobClaimedWithoutLease(t *testing.T) {
+	q, server := newTestQueue(t, time.Minute)
+
+	// Jobs claimed before leases were recorded are stored as plain JSON
+	raw := `{"id":"legacy","attempts":0,"enqueued_at":"2024-01-01T00:00:00Z","payload":"a|b"}`
+	server.ZAdd(q.inflightKey(), 0, raw)
+
+	if err := q.Promote(context.Background()); err != nil {
+		t.Fatalf("Promote: %v", err)
+	}
+
+	job := mustDequeue(t, q)
+	if job.ID != "legacy" || string(job.Payload) != `"a|b"` {
+		t.Errorf("job = %+v", job)
+	}
+}
+
+func TestWorkerHeartbeatKeepsSlowJob(t *testing.T) {
+	q, _ := newTestQueue(t, 60*time.Millisecond)
+	ctx := context.Background()
+
+	calls := 0
+	handler := func(ctx context.Context, job *Job) error {
+		calls++
+		deadline := time.Now().Add(200 * time.Millisecond)
+		for time.Now().Before(deadline) {
+			if err := q.Promote(ctx); err != nil {
+				t.Errorf("Promote: %v", err)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		return nil
+	}
+	pool := NewWorkerPool(q, handler, nil, 1, 3, time.Second)
+
+	q.Enqueue(ctx, "payload")
+	pool.process(ctx, mustDequeue(t, q))
+
+	if calls != 1 {
+		t.Errorf("handler called %d times, want 1", calls)
+	}
+	assertEmpty(t, q)
+}
@@ -0,0 +1,214 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	// pollInterval is how long idle workers wait before checking for new jobs
+	pollInterval = time.Second
+
+	// maxRetryDelay caps the exponential backoff between attempts
+	maxRetryDelay = time.Hour
+)
+
+// Handler processes a job. Returning an error schedules a retry unless the
+// error is wrapped with Permanent or the job is out of attempts.
+type Handler func(ctx context.Context, job *Job) error
+
+// DeadHandler is called when a job is moved to the dead-letter list
+type DeadHandler func(ctx context.Context, job *Job, err error)
+
+// permanentError marks failures that retrying cannot fix
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps an error so the job is dead-lettered without retrying
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// WorkerPool drains a queue with a fixed number of workers
+type WorkerPool struct {
+	queue       *Queue
+	handler     Handler
+	onDead      DeadHandler
+	concurrency int
+	maxAttempts int
+	baseDelay   time.Duration
+	wg          sync.WaitGroup
+}
+
+func NewWorkerPool(queue *Queue, handler Handler, onDead DeadHandler, concurrency, maxAttempts int, baseDelay time.Duration) *WorkerPool {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	return &WorkerPool{
+		queue:       queue,
+		handler:     handler,
+		onDead:      onDead,
+		concurrency: concurrency,
+		maxAttempts: maxAttempts,
+		baseDelay:   baseDelay,
+	}
+}
+
+// Start launches the workers and the promoter. They stop when ctx is cancelled.
+func (p *WorkerPool) Start(ctx context.Context) {
+	p.wg.Add(p.concurrency + 1)
+
+	go p.promote(ctx)
+	for i := 0; i < p.concurrency; i++ {
+		go p.work(ctx)
+	}
+
+	log.Printf("Started %d workers for queue %s", p.concurrency, p.queue.name)
+}
+
+// Wait blocks until all workers have stopped
+func (p *WorkerPool) Wait() {
+	p.wg.Wait()
+}
+
+// promote periodically requeues due retries and timed-out jobs
+func (p *WorkerPool) promote(ctx context.Context) {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := p.queue.Promote(ctx); err != nil && ctx.Err() == nil {
+				log.Printf("Queue %s: %v", p.queue.name, err)
+			}
+		}
+	}
+}
+
+func (p *WorkerPool) work(ctx context.Context) {
+	defer p.wg.Done()
+
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+
+		job, err := p.queue.Dequeue(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("Queue %s: %v", p.queue.name, err)
+		}
+
+		if job == nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(pollInterval):
+			}
+			continue
+		}
+
+		p.process(ctx, job)
+	}
+}
+
+// process runs the handler and acknowledges, retries or dead-letters the job.
+// Queue bookkeeping uses a fresh context so a shutdown mid-job doesn't lose it.
+func (p *WorkerPool) process(ctx context.Context, job *Job) {
+	stopHeartbeat := p.heartbeat(job)
+	err := p.handler(ctx, job)
+	stopHeartbeat()
+	bookkeeping := context.Background()
+
+	if err == nil {
+		if ackErr := p.queue.Ack(bookkeeping, job); ackErr != nil {
+			log.Printf("Queue %s: failed to ack job %s: %v", p.queue.name, job.ID, ackErr)
+		}
+		return
+	}
+
+	var permanent *permanentError
+	attempt := job.Attempts + 1
+
+	if errors.As(err, &permanent) || attempt >= p.maxAttempts {
+		log.Printf("Queue %s: job %s failed permanently after %d attempts: %v", p.queue.name, job.ID, attempt, err)
+		if dlErr := p.queue.DeadLetter(bookkeeping, job, err); dlErr != nil {
+			log.Printf("Queue %s: failed to dead-letter job %s: %v", p.queue.name, job.ID, dlErr)
+			if errors.Is(dlErr, ErrLeaseLost) {
+				return
+			}
+		}
+		if p.onDead != nil {
+			p.onDead(bookkeeping, job, err)
+		}
+		return
+	}
+
+	delay := p.backoff(attempt)
+	log.Printf("Queue %s: job %s failed (attempt %d/%d), retrying in %s: %v", p.queue.name, job.ID, attempt, p.maxAttempts, delay, err)
+	if retryErr := p.queue.Retry(bookkeeping, job, delay, err); retryErr != nil {
+		log.Printf("Queue %s: failed to schedule retry of job %s: %v", p.queue.name, job.ID, retryErr)
+	}
+}
+
+// heartbeat extends the job's visibility deadline while the handler runs, so
+// slow jobs are not handed to a second worker. The returned function stops it.
+func (p *WorkerPool) heartbeat(job *Job) func() {
+	interval := p.queue.visibilityTimeout / 3
+	if interval <= 0 {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := p.queue.Extend(context.Background(), job); err != nil {
+					log.Printf("Queue %s: failed to extend job %s: %v", p.queue.name, job.ID, err)
+					if errors.Is(err, ErrLeaseLost) {
+						return
+					}
+				}
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// backoff returns the delay before the next attempt: base * 2^(attempt-1)
+func (p *WorkerPool) backoff(attempt int) time.Duration {
+	delay := p.baseDelay << uint(attempt-1)
+	if delay <= 0 || delay > maxRetryDelay {
+		return maxRetryDelay
+	}
+	return delay
+}
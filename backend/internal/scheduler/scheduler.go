@@ -0,0 +1,113 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/shohag/seentics-email/internal/database"
+	"github.com/shohag/seentics-email/internal/mailer"
+	"github.com/shohag/seentics-email/internal/models"
+)
+
+const (
+	// batchSize is the number of due messages handled per tick
+	batchSize = 100
+
+	// pruneInterval is how often expired processed webhook events are deleted
+	pruneInterval = time.Hour
+)
+
+// Scheduler submits scheduled messages once their send time has come. It
+// also prunes the processed webhook events kept when Redis is unavailable.
+type Scheduler struct {
+	mailer     *mailer.Mailer
+	interval   time.Duration
+	lastPruned time.Time
+	wg         sync.WaitGroup
+}
+
+func New(mailer *mailer.Mailer, interval time.Duration) *Scheduler {
+	if interval <= 0 {
+		interval = 15 * time.Second
+	}
+
+	return &Scheduler{
+		mailer:   mailer,
+		interval: interval,
+	}
+}
+
+// Start runs the scheduler loop until ctx is cancelled
+func (s *Scheduler) Start(ctx context.Context) {
+	s.wg.Add(1)
+	go s.run(ctx)
+}
+
+// Wait blocks until the scheduler loop has stopped
+func (s *Scheduler) Wait() {
+	s.wg.Wait()
+}
+
+func (s *Scheduler) run(ctx context.Context) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.submitDue(ctx)
+			if time.Since(s.lastPruned) >= pruneInterval {
+				s.pruneProcessedEvents()
+				s.lastPruned = time.Now()
+			}
+		}
+	}
+}
+
+// submitDue hands every message whose send time has passed to the mailer
+func (s *Scheduler) submitDue(ctx context.Context) {
+	for ctx.Err() == nil {
+		var due []models.ScheduledEmail
+		err := database.DB.Where("send_at <= ?", time.Now()).
+			Order("send_at").Limit(batchSize).Find(&due).Error
+		if err != nil {
+			log.Printf("Failed to load scheduled emails: %v", err)
+			return
+		}
+
+		claimed := 0
+		for _, scheduled := range due {
+			ok, err := s.mailer.SubmitScheduled(ctx, scheduled)
+			if err != nil {
+				log.Printf("Failed to submit scheduled email %s: %v", scheduled.MessageID, err)
+			}
+			if ok {
+				claimed++
+			}
+		}
+
+		// Stop when the backlog is drained or nothing could be claimed
+		if len(due) < batchSize || claimed == 0 {
+			return
+		}
+	}
+}
+
+// pruneProcessedEvents deletes processed webhook events older than their TTL
+func (s *Scheduler) pruneProcessedEvents() {
+	result := database.DB.Where("created_at < ?", time.Now().Add(-models.ProcessedWebhookEventTTL)).
+		Delete(&models.ProcessedWebhookEvent{})
+	if result.Error != nil {
+		log.Printf("Failed to prune processed webhook events: %v", result.Error)
+		return
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("Pruned %d processed webhook events", result.RowsAffected)
+	}
+}
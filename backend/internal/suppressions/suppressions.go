@@ -0,0 +1,59 @@
+package suppressions
+
+import (
+	"strings"
+
+	"github.com/shohag/seentics-email/internal/database"
+	"github.com/shohag/seentics-email/internal/models"
+	"gorm.io/gorm/clause"
+)
+
+// Add suppresses an address for a user. It reports whether the address was
+// newly suppressed; an existing suppression is left unchanged.
+func Add(userID uint, email string, reason models.SuppressionReason, details string, emailLogID *uint) (bool, error) {
+	suppression := models.Suppression{
+		UserID:     userID,
+		Email:      Normalize(email),
+		Reason:     reason,
+		Details:    details,
+		EmailLogID: emailLogID,
+	}
+
+	result := database.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&suppression)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// Suppressed returns the subset of addresses suppressed for a user, keyed by
+// normalized address
+func Suppressed(userID uint, emails []string) (map[string]bool, error) {
+	suppressed := make(map[string]bool)
+	if len(emails) == 0 {
+		return suppressed, nil
+	}
+
+	normalized := make([]string, 0, len(emails))
+	for _, email := range emails {
+		normalized = append(normalized, Normalize(email))
+	}
+
+	var matches []string
+	err := database.DB.Model(&models.Suppression{}).
+		Where("user_id = ? AND email IN ?", userID, normalized).
+		Pluck("email", &matches).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, email := range matches {
+		suppressed[email] = true
+	}
+	return suppressed, nil
+}
+
+// Normalize returns the form addresses are stored and compared in
+func Normalize(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,175 @@
+package templates
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+	"text/template/parse"
+)
+
+// Preview renders a template leniently for review. Missing variables render
+// as empty values and are reported as warnings along with other likely
+// mistakes, instead of failing the render.
+func Preview(content Content, variables map[string]interface{}) (*Content, []string, error) {
+	if variables == nil {
+		variables = map[string]interface{}{}
+	}
+
+	var warnings []string
+	used := make(map[string]bool)
+
+	parts := []struct {
+		name string
+		text string
+	}{
+		{"subject", content.Subject},
+		{"html_body", content.HTMLBody},
+		{"plain_body", content.PlainBody},
+	}
+	for _, part := range parts {
+		tmpl, err := texttemplate.New(part.name).Parse(part.text)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", part.name, err)
+		}
+		for _, key := range referencedVariables(tmpl) {
+			used[key] = true
+			if _, ok := variables[key]; !ok {
+				warnings = append(warnings, fmt.Sprintf("%s references missing variable %q", part.name, key))
+			}
+		}
+	}
+
+	var unused []string
+	for key := range variables {
+		if !used[key] {
+			unused = append(unused, key)
+		}
+	}
+	sort.Strings(unused)
+	for _, key := range unused {
+		warnings = append(warnings, fmt.Sprintf("variable %q is not used by the template", key))
+	}
+
+	subject, err := previewText("subject", content.Subject, variables)
+	if err != nil {
+		return nil, nil, err
+	}
+	htmlBody, err := previewHTML("html_body", content.HTMLBody, variables)
+	if err != nil {
+		return nil, nil, err
+	}
+	plainBody, err := previewText("plain_body", content.PlainBody, variables)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if strings.ContainsAny(subject, "\r\n") {
+		warnings = append(warnings, "rendered subject contains a line break")
+	}
+
+	return &Content{Subject: subject, HTMLBody: htmlBody, PlainBody: plainBody}, warnings, nil
+}
+
+// referencedVariables lists the top-level variables a template reads, such
+// as name in {{.name}} or {{.name.first}}. Fields read inside range and with
+// blocks are relative to a different value and are not included.
+func referencedVariables(tmpl *texttemplate.Template) []string {
+	seen := make(map[string]bool)
+	var keys []string
+
+	add := func(key string) {
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+
+	var walk func(node parse.Node, topLevel bool)
+	walk = func(node parse.Node, topLevel bool) {
+		switch n := node.(type) {
+		case *parse.ListNode:
+			if n == nil {
+				return
+			}
+			for _, child := range n.Nodes {
+				walk(child, topLevel)
+			}
+		case *parse.ActionNode:
+			walk(n.Pipe, topLevel)
+		case *parse.PipeNode:
+			if n == nil {
+				return
+			}
+			for _, cmd := range n.Cmds {
+				walk(cmd, topLevel)
+			}
+		case *parse.CommandNode:
+			for _, arg := range n.Args {
+				walk(arg, topLevel)
+			}
+		case *parse.FieldNode:
+			if topLevel && len(n.Ident) > 0 {
+				add(n.Ident[0])
+			}
+		case *parse.VariableNode:
+			// $.name always refers to the top-level data
+			if len(n.Ident) > 1 && n.Ident[0] == "$" {
+				add(n.Ident[1])
+			}
+		case *parse.ChainNode:
+			walk(n.Node, topLevel)
+		case *parse.IfNode:
+			walk(n.Pipe, topLevel)
+			walk(n.List, topLevel)
+			walk(n.ElseList, topLevel)
+		case *parse.RangeNode:
+			walk(n.Pipe, topLevel)
+			walk(n.List, false)
+			walk(n.ElseList, topLevel)
+		case *parse.WithNode:
+			walk(n.Pipe, topLevel)
+			walk(n.List, false)
+			walk(n.ElseList, topLevel)
+		case *parse.TemplateNode:
+			walk(n.Pipe, topLevel)
+		}
+	}
+
+	for _, t := range tmpl.Templates() {
+		if t.Tree != nil {
+			walk(t.Tree.Root, true)
+		}
+	}
+
+	return keys
+}
+
+func previewText(name, text string, variables map[string]interface{}) (string, error) {
+	tmpl, err := texttemplate.New(name).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", name, err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, variables); err != nil {
+		return "", fmt.Errorf("%s: %w", name, err)
+	}
+	// A missing key in a map[string]interface{} renders as <no value>
+	return strings.ReplaceAll(buf.String(), "<no value>", ""), nil
+}
+
+func previewHTML(name, text string, variables map[string]interface{}) (string, error) {
+	tmpl, err := htmltemplate.New(name).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", name, err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, variables); err != nil {
+		return "", fmt.Errorf("%s: %w", name, err)
+	}
+	return buf.String(), nil
+}
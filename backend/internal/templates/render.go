@@ -0,0 +1,99 @@
+package templates
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+)
+
+// Content is the subject and bodies of an email, before or after rendering
+type Content struct {
+	Subject   string
+	HTMLBody  string
+	PlainBody string
+}
+
+// Validate parses each part so syntax errors are reported when a template is saved
+func Validate(content Content) error {
+	if _, err := texttemplate.New("subject").Parse(content.Subject); err != nil {
+		return fmt.Errorf("subject: %w", err)
+	}
+	if _, err := htmltemplate.New("html_body").Parse(content.HTMLBody); err != nil {
+		return fmt.Errorf("html_body: %w", err)
+	}
+	if _, err := texttemplate.New("plain_body").Parse(content.PlainBody); err != nil {
+		return fmt.Errorf("plain_body: %w", err)
+	}
+	return nil
+}
+
+// Render executes a template with the given variables. The HTML body is
+// rendered with html/template so variables are escaped for their context;
+// the subject and plain body use text/template. A variable referenced by the
+// template but missing from variables is an error.
+func Render(content Content, variables map[string]interface{}) (*Content, error) {
+	if variables == nil {
+		variables = map[string]interface{}{}
+	}
+
+	subject, err := renderText("subject", content.Subject, variables)
+	if err != nil {
+		return nil, err
+	}
+	if strings.ContainsAny(subject, "\r\n") {
+		return nil, errors.New("subject: rendered subject contains a line break")
+	}
+
+	htmlBody, err := renderHTML("html_body", content.HTMLBody, variables)
+	if err != nil {
+		return nil, err
+	}
+
+	plainBody, err := renderText("plain_body", content.PlainBody, variables)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Content{
+		Subject:   subject,
+		HTMLBody:  htmlBody,
+		PlainBody: plainBody,
+	}, nil
+}
+
+func renderText(name, text string, variables map[string]interface{}) (string, error) {
+	if text == "" {
+		return "", nil
+	}
+
+	tmpl, err := texttemplate.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", name, err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, variables); err != nil {
+		return "", fmt.Errorf("%s: %w", name, err)
+	}
+	return buf.String(), nil
+}
+
+func renderHTML(name, text string, variables map[string]interface{}) (string, error) {
+	if text == "" {
+		return "", nil
+	}
+
+	tmpl, err := htmltemplate.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", name, err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, variables); err != nil {
+		return "", fmt.Errorf("%s: %w", name, err)
+	}
+	return buf.String(), nil
+}
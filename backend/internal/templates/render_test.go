@@ -0,0 +1,91 @@
+package templates
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   Content
+		variables map[string]interface{}
+		want      *Content
+		wantErr   string
+	}{
+		{
+			name: "all variables present",
+			content: Content{
+				Subject:   "Welcome, {{.name}}",
+				HTMLBody:  "<p>Hello {{.user.first}}</p>",
+				PlainBody: "Hello {{.user.first}}",
+			},
+			variables: map[string]interface{}{"name": "Ada", "user": map[string]interface{}{"first": "Ada"}},
+			want: &Content{
+				Subject:   "Welcome, Ada",
+				HTMLBody:  "<p>Hello Ada</p>",
+				PlainBody: "Hello Ada",
+			},
+		},
+		{
+			name:    "missing top-level variable in the subject",
+			content: Content{Subject: "Welcome, {{.name}}"},
+			wantErr: `map has no entry for key "name"`,
+		},
+		{
+			name:      "missing nested variable in the HTML body",
+			content:   Content{Subject: "Hi", HTMLBody: "<p>{{.user.first}}</p>"},
+			variables: map[string]interface{}{"user": map[string]interface{}{"last": "Lovelace"}},
+			wantErr:   `map has no entry for key "first"`,
+		},
+		{
+			name:      "missing nested variable in the plain body",
+			content:   Content{Subject: "Hi", PlainBody: "{{.user.first}}"},
+			variables: map[string]interface{}{},
+			wantErr:   `map has no entry for key "user"`,
+		},
+		{
+			name:      "HTML is escaped for its context",
+			content:   Content{Subject: "{{.name}}", HTMLBody: `<a href="https://example.com/?q={{.name}}">{{.name}}</a>`, PlainBody: "{{.name}}"},
+			variables: map[string]interface{}{"name": `<b>"A&B"</b>`},
+			want: &Content{
+				Subject:   `<b>"A&B"</b>`,
+				HTMLBody:  `<a href="https://example.com/?q=%3cb%3e%22A%26B%22%3c%2fb%3e">&lt;b&gt;&#34;A&amp;B&#34;&lt;/b&gt;</a>`,
+				PlainBody: `<b>"A&B"</b>`,
+			},
+		},
+		{
+			name:      "subject with an injected header",
+			content:   Content{Subject: "Hello {{.name}}"},
+			variables: map[string]interface{}{"name": "Ada\r\nBcc: victim@example.com"},
+			wantErr:   "subject: rendered subject contains a line break",
+		},
+		{
+			name:      "subject with a bare newline",
+			content:   Content{Subject: "Hello {{.name}}"},
+			variables: map[string]interface{}{"name": "Ada\nX"},
+			wantErr:   "subject: rendered subject contains a line break",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Render(tt.content, tt.variables)
+			if tt.wantErr != "" {
+				if err == nil {
+					t.Fatalf("expected an error, got %+v", got)
+				}
+				if !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("error = %q, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Render: %v", err)
+			}
+			if *got != *tt.want {
+				t.Errorf("got %+v\nwant %+v", *got, *tt.want)
+			}
+		})
+	}
+}
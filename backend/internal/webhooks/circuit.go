@@ -0,0 +1,72 @@
+package webhooks
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/shohag/seentics-email/internal/database"
+	"github.com/shohag/seentics-email/internal/models"
+	"github.com/shohag/seentics-email/internal/notifications"
+	"gorm.io/gorm"
+)
+
+// recordOutcome updates the webhook's failure streak after an attempt and
+// disables the endpoint once it has been failing for too long
+func (d *Dispatcher) recordOutcome(webhook models.Webhook, success bool, now time.Time) {
+	if success {
+		database.DB.Model(&models.Webhook{}).
+			Where("id = ? AND (consecutive_failures > 0 OR failing_since IS NOT NULL)", webhook.ID).
+			Updates(map[string]interface{}{
+				"consecutive_failures": 0,
+				"failing_since":        nil,
+			})
+		return
+	}
+
+	database.DB.Model(&models.Webhook{}).Where("id = ?", webhook.ID).Updates(map[string]interface{}{
+		"consecutive_failures": gorm.Expr("consecutive_failures + 1"),
+		"failing_since":        gorm.Expr("COALESCE(failing_since, ?)", now),
+	})
+
+	var current models.Webhook
+	if err := database.DB.First(&current, webhook.ID).Error; err != nil || !current.IsActive {
+		return
+	}
+
+	var reason string
+	switch {
+	case d.disableAfterFailures > 0 && current.ConsecutiveFailures >= d.disableAfterFailures:
+		reason = fmt.Sprintf("%d consecutive failed deliveries", current.ConsecutiveFailures)
+	case d.disableAfterDuration > 0 && current.FailingSince != nil && now.Sub(*current.FailingSince) >= d.disableAfterDuration:
+		reason = fmt.Sprintf("deliveries failing since %s", current.FailingSince.UTC().Format(time.RFC3339))
+	default:
+		return
+	}
+
+	d.disable(current, reason, now)
+}
+
+// disable deactivates a webhook and notifies its owner. The is_active guard
+// makes sure concurrent deliveries only raise one notification.
+func (d *Dispatcher) disable(webhook models.Webhook, reason string, now time.Time) {
+	result := database.DB.Model(&models.Webhook{}).
+		Where("id = ? AND is_active = ?", webhook.ID, true).
+		Updates(map[string]interface{}{
+			"is_active":       false,
+			"disabled_reason": reason,
+			"disabled_at":     now,
+		})
+	if result.Error != nil || result.RowsAffected == 0 {
+		return
+	}
+
+	log.Printf("Webhook %d disabled: %s", webhook.ID, reason)
+
+	notifications.Notify(
+		webhook.UserID,
+		models.NotificationWebhookDisabled,
+		"Webhook endpoint disabled",
+		fmt.Sprintf("Deliveries to %s were stopped after %s. Fix the endpoint and send a test event to re-enable it.", webhook.URL, reason),
+	)
+}
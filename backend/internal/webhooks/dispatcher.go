@@ -0,0 +1,301 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shohag/seentics-email/internal/config"
+	"github.com/shohag/seentics-email/internal/database"
+	"github.com/shohag/seentics-email/internal/models"
+	"github.com/shohag/seentics-email/internal/queue"
+	"gorm.io/gorm"
+)
+
+const (
+	// maxResponseExcerpt is how much of the endpoint's response body we store
+	maxResponseExcerpt = 4 << 10
+
+	// maxRetryDelay caps the backoff of deliveries retried without the queue
+	maxRetryDelay = 6 * time.Hour
+)
+
+// Dispatcher delivers events to user webhook endpoints, through the delivery
+// queue when one is configured
+type Dispatcher struct {
+	client      *http.Client
+	queue       *queue.Queue
+	maxAttempts int
+	baseDelay   time.Duration
+
+	disableAfterFailures int
+	disableAfterDuration time.Duration
+}
+
+// queuedDelivery is the job payload for delivering an event to one webhook
+type queuedDelivery struct {
+	WebhookID uint            `json:"webhook_id"`
+	EventID   string          `json:"event_id"`
+	EventType string          `json:"event_type"`
+	Body      json.RawMessage `json:"body"`
+}
+
+func NewDispatcher(cfg *config.Config, deliveryQueue *queue.Queue) *Dispatcher {
+	maxAttempts := cfg.WebhookMaxAttempts
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	return &Dispatcher{
+		client:      newHTTPClient(cfg.WebhookTimeout),
+		queue:       deliveryQueue,
+		maxAttempts: maxAttempts,
+		baseDelay:   cfg.WebhookRetryBaseDelay,
+
+		disableAfterFailures: cfg.WebhookDisableAfterFailures,
+		disableAfterDuration: cfg.WebhookDisableAfterDuration,
+	}
+}
+
+// Dispatch sends an event to every active webhook of the user subscribed to it.
+// Deliveries are queued and retried with exponential backoff by the webhook
+// workers.
+func (d *Dispatcher) Dispatch(userID uint, eventType string, data interface{}) {
+	var active []models.Webhook
+	if err := database.DB.Where("user_id = ? AND is_active = ?", userID, true).Find(&active).Error; err != nil {
+		log.Printf("Failed to load webhooks for user %d: %v", userID, err)
+		return
+	}
+
+	var webhooks []models.Webhook
+	for _, webhook := range active {
+		if Subscribed(webhook, eventType) {
+			webhooks = append(webhooks, webhook)
+		}
+	}
+
+	if len(webhooks) == 0 {
+		return
+	}
+
+	event := NewEvent(eventType, data)
+	body, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Failed to marshal webhook event %s: %v", event.ID, err)
+		return
+	}
+
+	for _, webhook := range webhooks {
+		d.enqueue(webhook, event.ID, event.Type, body)
+	}
+}
+
+// enqueue queues a delivery to one webhook. Without a queue, or if
+// enqueueing fails, the delivery is retried in the background instead; those
+// retries are lost if the server restarts.
+func (d *Dispatcher) enqueue(webhook models.Webhook, eventID, eventType string, body []byte) {
+	if d.queue != nil {
+		err := d.queue.Enqueue(context.Background(), queuedDelivery{
+			WebhookID: webhook.ID,
+			EventID:   eventID,
+			EventType: eventType,
+			Body:      body,
+		})
+		if err == nil {
+			return
+		}
+		log.Printf("Failed to queue webhook %d delivery of %s, retrying in process: %v", webhook.ID, eventID, err)
+	}
+
+	go d.deliverWithRetry(webhook, eventID, eventType, body)
+}
+
+// deliverWithRetry attempts delivery until it succeeds or attempts run out.
+// The webhook is reloaded before each retry so that URL changes, secret
+// rotations and deactivation made in the meantime are respected.
+func (d *Dispatcher) deliverWithRetry(webhook models.Webhook, eventID, eventType string, body []byte) {
+	for attempt := 1; attempt <= d.maxAttempts; attempt++ {
+		if attempt > 1 {
+			if err := database.DB.Where("id = ? AND is_active = ?", webhook.ID, true).First(&webhook).Error; err != nil {
+				log.Printf("Webhook %d no longer active, dropping %s", webhook.ID, eventID)
+				return
+			}
+		}
+
+		delivery := d.send(webhook, eventID, eventType, body, attempt)
+		if delivery.Success {
+			return
+		}
+
+		log.Printf("Webhook %d delivery of %s failed (attempt %d/%d): %s",
+			webhook.ID, eventID, attempt, d.maxAttempts, delivery.Error)
+
+		if attempt < d.maxAttempts {
+			time.Sleep(d.backoff(attempt))
+		}
+	}
+}
+
+// HandleJob performs one queued delivery attempt. The webhook is reloaded for
+// every attempt so that URL changes, secret rotations and deactivation made
+// in the meantime are respected.
+func (d *Dispatcher) HandleJob(ctx context.Context, job *queue.Job) error {
+	var queued queuedDelivery
+	if err := json.Unmarshal(job.Payload, &queued); err != nil {
+		return queue.Permanent(fmt.Errorf("invalid webhook job payload: %w", err))
+	}
+
+	var webhook models.Webhook
+	err := database.DB.Where("id = ? AND is_active = ?", queued.WebhookID, true).First(&webhook).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Printf("Webhook %d no longer active, dropping %s", queued.WebhookID, queued.EventID)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	delivery := d.send(webhook, queued.EventID, queued.EventType, queued.Body, job.Attempts+1)
+	if !delivery.Success {
+		return errors.New(delivery.Error)
+	}
+
+	return nil
+}
+
+// HandleDeadJob is called when a queued delivery has used up its attempts.
+// Every attempt is already recorded as a delivery, so the event can still be
+// redelivered from the deliveries list.
+func (d *Dispatcher) HandleDeadJob(ctx context.Context, job *queue.Job, err error) {
+	var queued queuedDelivery
+	if jsonErr := json.Unmarshal(job.Payload, &queued); jsonErr != nil {
+		log.Printf("Dropped invalid webhook job %s: %v", job.ID, err)
+		return
+	}
+
+	log.Printf("Webhook %d delivery of %s gave up after %d attempts: %v",
+		queued.WebhookID, queued.EventID, job.Attempts+1, err)
+}
+
+// Redeliver replays a previously attempted delivery once, synchronously,
+// and returns the new attempt record
+func (d *Dispatcher) Redeliver(webhook models.Webhook, previous models.WebhookDelivery) (*models.WebhookDelivery, error) {
+	var attempts int64
+	database.DB.Model(&models.WebhookDelivery{}).
+		Where("webhook_id = ? AND event_id = ?", webhook.ID, previous.EventID).
+		Count(&attempts)
+
+	delivery := d.send(webhook, previous.EventID, previous.EventType, []byte(previous.RequestBody), int(attempts)+1)
+	if delivery.ID == 0 {
+		return delivery, fmt.Errorf("failed to record delivery attempt")
+	}
+
+	return delivery, nil
+}
+
+// Test sends a synthetic signed event to a webhook once, synchronously,
+// regardless of its subscriptions or active state
+func (d *Dispatcher) Test(webhook models.Webhook) (*models.WebhookDelivery, error) {
+	event := NewEvent(EventWebhookTest, map[string]interface{}{
+		"webhook_id": webhook.ID,
+		"message":    "This is a test event from Seentics Email",
+	})
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal test event: %w", err)
+	}
+
+	delivery := d.send(webhook, event.ID, event.Type, body, 1)
+	if delivery.ID == 0 {
+		return delivery, fmt.Errorf("failed to record delivery attempt")
+	}
+
+	return delivery, nil
+}
+
+// send performs a single signed delivery attempt and records it
+func (d *Dispatcher) send(webhook models.Webhook, eventID, eventType string, body []byte, attempt int) *models.WebhookDelivery {
+	now := time.Now()
+	database.DB.Model(&models.Webhook{}).Where("id = ?", webhook.ID).Update("last_triggered_at", now)
+
+	delivery := &models.WebhookDelivery{
+		WebhookID:   webhook.ID,
+		UserID:      webhook.UserID,
+		EventID:     eventID,
+		EventType:   eventType,
+		Attempt:     attempt,
+		RequestBody: string(body),
+	}
+
+	status, responseBody, err := d.post(webhook, eventID, eventType, body, now)
+	delivery.LatencyMs = time.Since(now).Milliseconds()
+	delivery.ResponseStatus = status
+	delivery.ResponseBody = responseBody
+
+	switch {
+	case err != nil:
+		delivery.Error = err.Error()
+	case status < 200 || status >= 300:
+		delivery.Error = fmt.Sprintf("endpoint returned status %d", status)
+	default:
+		delivery.Success = true
+	}
+
+	if err := database.DB.Create(delivery).Error; err != nil {
+		log.Printf("Failed to record webhook delivery for %s: %v", eventID, err)
+	}
+
+	d.recordOutcome(webhook, delivery.Success, now)
+
+	return delivery
+}
+
+// post sends the signed request and returns the status code and a response excerpt
+func (d *Dispatcher) post(webhook models.Webhook, eventID, eventType string, body []byte, now time.Time) (int, string, error) {
+	req, err := http.NewRequest("POST", webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to create request: %w", err)
+	}
+
+	timestamp := now.Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Seentics-Webhooks/1.0")
+	req.Header.Set(HeaderEventID, eventID)
+	req.Header.Set(HeaderEventType, eventType)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, SignatureHeader(signingSecrets(webhook, now), timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	excerpt, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseExcerpt))
+
+	// Drain the rest so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	// Postgres text columns reject NUL bytes and invalid UTF-8
+	cleaned := strings.ToValidUTF8(strings.ReplaceAll(string(excerpt), "\x00", ""), "")
+
+	return resp.StatusCode, cleaned, nil
+}
+
+// backoff returns the delay before the next attempt: base * 2^(attempt-1)
+func (d *Dispatcher) backoff(attempt int) time.Duration {
+	delay := d.baseDelay << uint(attempt-1)
+	if delay <= 0 || delay > maxRetryDelay {
+		return maxRetryDelay
+	}
+	return delay
+}
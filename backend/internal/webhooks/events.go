@@ -0,0 +1,184 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shohag/seentics-email/internal/models"
+	"github.com/shohag/seentics-email/internal/postal"
+)
+
+// Event types delivered to user webhook endpoints
+const (
+	EventMessageSent      = "message.sent"
+	EventMessageDelivered = "message.delivered"
+	EventMessageBounced   = "message.bounced"
+	EventMessageFailed    = "message.failed"
+	EventMessageHeld      = "message.held"
+	EventMessageOpened    = "message.opened"
+	EventMessageClicked   = "message.clicked"
+	EventMessageComplaint = "message.complaint"
+
+	EventDomainVerificationChanged = "domain.verification_changed"
+)
+
+// EventWebhookTest is sent by the test-ping endpoint and bypasses subscriptions
+const EventWebhookTest = "webhook.test"
+
+// postalEventTypes maps Postal webhook events to our public event types
+var postalEventTypes = map[string]string{
+	postal.EventMessageSent:      EventMessageSent,
+	postal.EventMessageDelivered: EventMessageDelivered,
+	postal.EventMessageBounced:   EventMessageBounced,
+	postal.EventMessageFailed:    EventMessageFailed,
+	postal.EventMessageHeld:      EventMessageHeld,
+	postal.EventMessageOpened:    EventMessageOpened,
+	postal.EventMessageClicked:   EventMessageClicked,
+}
+
+// ownEventTypes are raised by this service rather than forwarded from Postal
+var ownEventTypes = []string{
+	EventMessageComplaint,
+	EventDomainVerificationChanged,
+}
+
+// wildcardAll subscribes a webhook to every event
+const wildcardAll = "*"
+
+// Event is the envelope posted to user webhook endpoints
+type Event struct {
+	ID        string      `json:"id"`
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// NewEvent creates an event envelope with a unique ID
+func NewEvent(eventType string, data interface{}) *Event {
+	return &Event{
+		ID:        "evt_" + uuid.New().String(),
+		Type:      eventType,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	}
+}
+
+// EventTypeFromPostal returns the public event type for a Postal event,
+// or an empty string if the event is not forwarded to users
+func EventTypeFromPostal(postalEvent string) string {
+	return postalEventTypes[postalEvent]
+}
+
+// EventTypes returns the sorted catalogue of events users can subscribe to
+func EventTypes() []string {
+	types := make([]string, 0, len(postalEventTypes)+len(ownEventTypes))
+	for _, eventType := range postalEventTypes {
+		types = append(types, eventType)
+	}
+	types = append(types, ownEventTypes...)
+	sort.Strings(types)
+	return types
+}
+
+// ValidateEventTypes normalizes a subscription list and checks every entry
+// against the catalogue. Entries may be exact types, "<prefix>.*" or "*".
+func ValidateEventTypes(events []string) ([]string, error) {
+	if len(events) == 0 {
+		return nil, fmt.Errorf("at least one event type is required")
+	}
+
+	catalogue := EventTypes()
+	seen := make(map[string]bool)
+	normalized := make([]string, 0, len(events))
+
+	for _, event := range events {
+		event = strings.ToLower(strings.TrimSpace(event))
+		if seen[event] {
+			continue
+		}
+
+		if !isKnownPattern(event, catalogue) {
+			return nil, fmt.Errorf("unknown event type %q", event)
+		}
+
+		seen[event] = true
+		normalized = append(normalized, event)
+	}
+
+	return normalized, nil
+}
+
+// Matches reports whether an event type is covered by a subscription list
+func Matches(patterns []string, eventType string) bool {
+	for _, pattern := range patterns {
+		if matchPattern(pattern, eventType) {
+			return true
+		}
+	}
+	return false
+}
+
+// Subscribed reports whether a webhook should receive an event type.
+// Webhooks created before subscriptions were stored have an empty list
+// and keep receiving every event.
+func Subscribed(webhook models.Webhook, eventType string) bool {
+	events := DecodeEvents(webhook.Events)
+	if len(events) == 0 {
+		return true
+	}
+	return Matches(events, eventType)
+}
+
+// EncodeEvents serializes a subscription list for the jsonb column
+func EncodeEvents(events []string) (string, error) {
+	if events == nil {
+		events = []string{}
+	}
+	data, err := json.Marshal(events)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode events: %w", err)
+	}
+	return string(data), nil
+}
+
+// DecodeEvents parses a subscription list stored in the jsonb column
+func DecodeEvents(raw string) []string {
+	var events []string
+	if raw == "" || json.Unmarshal([]byte(raw), &events) != nil {
+		return []string{}
+	}
+	return events
+}
+
+// isKnownPattern checks a single normalized entry against the catalogue
+func isKnownPattern(pattern string, catalogue []string) bool {
+	if pattern == wildcardAll {
+		return true
+	}
+
+	for _, eventType := range catalogue {
+		if strings.HasSuffix(pattern, ".*") {
+			if matchPattern(pattern, eventType) {
+				return true
+			}
+		} else if pattern == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// matchPattern matches "*", "<prefix>.*" and exact event types
+func matchPattern(pattern, eventType string) bool {
+	if pattern == wildcardAll {
+		return true
+	}
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok && strings.HasSuffix(prefix, ".") {
+		return strings.HasPrefix(eventType, prefix)
+	}
+	return pattern == eventType
+}
@@ -0,0 +1,76 @@
+package webhooks
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/shohag/seentics-email/internal/models"
+)
+
+func TestValidateEventTypes(t *testing.T) {
+	tests := []struct {
+		name    string
+		events  []string
+		want    []string
+		wantErr bool
+	}{
+		{name: "exact type", events: []string{"message.delivered"}, want: []string{"message.delivered"}},
+		{name: "everything", events: []string{"*"}, want: []string{"*"}},
+		{name: "message wildcard", events: []string{"message.*"}, want: []string{"message.*"}},
+		{name: "domain wildcard", events: []string{"domain.*"}, want: []string{"domain.*"}},
+		{name: "case and spaces", events: []string{" Message.Bounced ", "MESSAGE.*"}, want: []string{"message.bounced", "message.*"}},
+		{name: "duplicates after normalization", events: []string{"message.sent", " message.SENT"}, want: []string{"message.sent"}},
+		{name: "unknown type", events: []string{"message.delivered", "message.exploded"}, wantErr: true},
+		{name: "unknown prefix", events: []string{"billing.*"}, wantErr: true},
+		{name: "partial wildcard", events: []string{"mess*"}, wantErr: true},
+		{name: "test events cannot be subscribed to", events: []string{"webhook.test"}, wantErr: true},
+		{name: "empty list", events: []string{}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ValidateEventTypes(tt.events)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ValidateEventTypes: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSubscribed(t *testing.T) {
+	tests := []struct {
+		name      string
+		events    string // Stored jsonb value
+		eventType string
+		want      bool
+	}{
+		{"everything", `["*"]`, EventDomainVerificationChanged, true},
+		{"message wildcard matches message events", `["message.*"]`, EventMessageComplaint, true},
+		{"message wildcard skips domain events", `["message.*"]`, EventDomainVerificationChanged, false},
+		{"domain wildcard", `["domain.*"]`, EventDomainVerificationChanged, true},
+		{"domain wildcard skips message events", `["domain.*"]`, EventMessageSent, false},
+		{"exact match", `["message.bounced","message.delivered"]`, EventMessageDelivered, true},
+		{"exact miss", `["message.bounced"]`, EventMessageDelivered, false},
+		{"prefix is not a wildcard", `["message.sen"]`, EventMessageSent, false},
+		{"legacy empty list", ``, EventMessageOpened, true},
+		{"legacy empty array", `[]`, EventDomainVerificationChanged, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			webhook := models.Webhook{Events: tt.events}
+			if got := Subscribed(webhook, tt.eventType); got != tt.want {
+				t.Errorf("Subscribed(%s, %s) = %v, want %v", tt.events, tt.eventType, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shohag/seentics-email/internal/models"
+)
+
+// Headers set on every outbound webhook request
+const (
+	HeaderEventID   = "X-Seentics-Event-ID"
+	HeaderEventType = "X-Seentics-Event"
+	HeaderTimestamp = "X-Seentics-Timestamp"
+	HeaderSignature = "X-Seentics-Signature"
+)
+
+// Sign computes the HMAC-SHA256 signature of "<timestamp>.<body>" using the
+// webhook secret. Receivers recompute it to verify a request came from us.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// SignatureHeader builds the X-Seentics-Signature value, e.g. "t=1700000000,v1=5f2b...".
+// During a secret rotation one v1 entry is added per valid secret, and
+// receivers should accept the request if any of them matches.
+func SignatureHeader(secrets []string, timestamp int64, body []byte) string {
+	parts := []string{fmt.Sprintf("t=%d", timestamp)}
+	for _, secret := range secrets {
+		parts = append(parts, "v1="+Sign(secret, timestamp, body))
+	}
+	return strings.Join(parts, ",")
+}
+
+// signingSecrets returns the secrets that currently sign deliveries for a webhook
+func signingSecrets(webhook models.Webhook, now time.Time) []string {
+	secrets := []string{webhook.Secret}
+	if webhook.PreviousSecret != "" && webhook.PreviousSecretExpiresAt != nil && now.Before(*webhook.PreviousSecretExpiresAt) {
+		secrets = append(secrets, webhook.PreviousSecret)
+	}
+	return secrets
+}